@@ -0,0 +1,153 @@
+// Package bencode implements the encoding of .torrent files, session files
+// and the other BitTorrent metadata, as defined by BEP 3.
+//
+// Marshal and Unmarshal map bencode to Go values much like encoding/json:
+//
+//	integer     int*, uint* and bool (0 is false)
+//	string      string, []byte and byte arrays
+//	list        slices and arrays
+//	dictionary  structs and maps with string keys
+//
+// Struct fields are matched by the `bencode:"name,omitempty"` tag, falling
+// back to the field name; matching is case sensitive and fields tagged "-" are
+// skipped. Into an interface{}, integers decode to int64, strings to string,
+// lists to []interface{} and dictionaries to map[string]interface{}.
+//
+// A RawMessage keeps the exact encoding of a value, such as the info
+// dictionary whose SHA-1 is a torrent's info-hash, and a Decoder reads values
+// one after the other from a stream. Input is decoded leniently by default;
+// UnmarshalStrict and Decoder.Strict only accept the canonical encoding.
+package bencode
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Marshaler is implemented by types that encode themselves to bencode. The
+// result must be a single valid bencoded value.
+type Marshaler interface {
+	MarshalBencode() ([]byte, error)
+}
+
+// Unmarshaler is implemented by types that decode themselves from bencode.
+// UnmarshalBencode gets the encoding of a single value, and must copy it to
+// keep it after returning.
+type Unmarshaler interface {
+	UnmarshalBencode([]byte) error
+}
+
+// RawMessage is a raw encoded bencode value. It is kept as is when decoding
+// and written as is when encoding, so that e.g. an info dictionary can be
+// hashed or re-encoded exactly as it was read.
+type RawMessage []byte
+
+// MarshalBencode returns m as the encoding of m
+func (m RawMessage) MarshalBencode() ([]byte, error) {
+	if len(m) == 0 {
+		return nil, &SyntaxError{Msg: "empty RawMessage"}
+	}
+	return m, nil
+}
+
+// UnmarshalBencode sets *m to a copy of data
+func (m *RawMessage) UnmarshalBencode(data []byte) error {
+	*m = append((*m)[:0], data...)
+	return nil
+}
+
+var (
+	marshalerType   = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+)
+
+// SyntaxError is returned for malformed input, or input not in canonical
+// form in strict mode
+type SyntaxError struct {
+	// Msg describes the error
+	Msg string
+	// Offset is the position of the error in the input, in bytes
+	Offset int64
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("bencode: %s at offset %d", e.Msg, e.Offset)
+}
+
+// DecodeError is returned by Unmarshal when a value can't be stored into the
+// destination type
+type DecodeError struct {
+	// Path locates the offending value within the destination, e.g.
+	// "info.files[3].length"
+	Path string
+	// Value is the kind of bencode value: "integer", "string", "list" or
+	// "dictionary"
+	Value string
+	// Type is the Go type it should have been decoded into
+	Type reflect.Type
+	// Offset is the position of the value in the input, in bytes
+	Offset int64
+}
+
+func (e *DecodeError) Error() string {
+	msg := fmt.Sprintf("bencode: cannot decode %s into %v", e.Value, e.Type)
+	if e.Path != "" {
+		msg += " at " + e.Path
+	}
+	return fmt.Sprintf("%s (offset %d)", msg, e.Offset)
+}
+
+// UnsupportedTypeError is returned by Marshal for values that have no bencode
+// representation, such as floats, channels or nil pointers outside of
+// dictionaries
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return fmt.Sprintf("bencode: unsupported type %v", e.Type)
+}
+
+type fieldInfo struct {
+	index     int
+	name      string
+	omitEmpty bool
+}
+
+// fieldTag parses the `bencode:"name,omitempty"` tag of sf, falling back to
+// the field name. ok is false for fields tagged "-", which are skipped.
+func fieldTag(sf reflect.StructField) (name string, omitEmpty bool, ok bool) {
+	parts := strings.Split(sf.Tag.Get("bencode"), ",")
+	if parts[0] == "-" && len(parts) == 1 {
+		return "", false, false
+	}
+	name = parts[0]
+	if name == "" {
+		name = sf.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, true
+}
+
+// structFields lists the exported, non-skipped fields of t with their keys.
+// It is shared by Marshal and Unmarshal, so both directions agree on keys.
+func structFields(t reflect.Type) []fieldInfo {
+	var fields []fieldInfo
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" { // unexported
+			continue
+		}
+		name, omitEmpty, ok := fieldTag(sf)
+		if !ok {
+			continue
+		}
+		fields = append(fields, fieldInfo{index: i, name: name, omitEmpty: omitEmpty})
+	}
+	return fields
+}
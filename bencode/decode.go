@@ -0,0 +1,481 @@
+package bencode
+
+import (
+	"reflect"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// maxDepth bounds the nesting of lists and dictionaries
+const maxDepth = 10000
+
+// Unmarshal decodes the single bencoded value `data` into the value pointed
+// to by v, see the package documentation for the mapping of Go values.
+// Dictionary keys without a matching struct field are ignored.
+//
+// Input is accepted leniently, as found in the wild: integers and string
+// lengths with leading zeros, unsorted dictionary keys and, for duplicate
+// keys, the last value wins. Malformed input yields a *SyntaxError, and a
+// value that doesn't fit its destination a *DecodeError.
+func Unmarshal(data []byte, v interface{}) error {
+	return unmarshal(data, v, false, 0)
+}
+
+// UnmarshalStrict is like Unmarshal, but only accepts the canonical encoding
+// defined by BEP 3, the one Marshal produces: no leading zeros or negative
+// zero, and dictionary keys sorted without duplicates. This matters when the
+// encoding is hashed, as the info dictionary of a torrent.
+func UnmarshalStrict(data []byte, v interface{}) error {
+	return unmarshal(data, v, true, 0)
+}
+
+// Decode decodes the single bencoded value `data` into an interface{}, see
+// Unmarshal
+func Decode(data []byte) (interface{}, error) {
+	var v interface{}
+	err := Unmarshal(data, &v)
+	return v, err
+}
+
+// RawValue returns the exact encoding of the value stored under `key` in the
+// top-level dictionary of data, e.g. the "info" dictionary of a .torrent,
+// whose SHA-1 is the torrent's info-hash.
+func RawValue(data []byte, key string) ([]byte, error) {
+	d := &decoder{data: data}
+	if !d.consume('d') {
+		return nil, d.syntaxError("not a dictionary")
+	}
+	for prev, first := "", true; !d.consume('e'); first = false {
+		k, err := d.key(prev, first)
+		if err != nil {
+			return nil, err
+		}
+		prev = k
+		start := d.pos
+		if err := d.skip(); err != nil {
+			return nil, err
+		}
+		if k == key {
+			return data[start:d.pos], nil
+		}
+	}
+	return nil, errors.Errorf("bencode: key %q not found", key)
+}
+
+func unmarshal(data []byte, v interface{}, strict bool, offset int64) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.Errorf("bencode: Unmarshal requires a non-nil pointer, got %T", v)
+	}
+	d := &decoder{data: data, strict: strict, offset: offset}
+	if err := d.value(rv.Elem(), ""); err != nil {
+		return err
+	}
+	if d.pos != len(data) {
+		return d.syntaxError("trailing data")
+	}
+	return nil
+}
+
+// checkValid returns an error unless data is a single well formed value
+func checkValid(data []byte) error {
+	d := &decoder{data: data}
+	if err := d.skip(); err != nil {
+		return err
+	}
+	if d.pos != len(data) {
+		return d.syntaxError("trailing data")
+	}
+	return nil
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+	// offset is the position of data within the input, for errors
+	offset int64
+	strict bool
+	depth  int
+}
+
+func (d *decoder) syntaxError(msg string) error {
+	return &SyntaxError{Msg: msg, Offset: d.offset + int64(d.pos)}
+}
+
+func (d *decoder) decodeError(value string, v reflect.Value, path string, start int) error {
+	return &DecodeError{Path: path, Value: value, Type: v.Type(), Offset: d.offset + int64(start)}
+}
+
+func (d *decoder) consume(c byte) bool {
+	if d.pos < len(d.data) && d.data[d.pos] == c {
+		d.pos++
+		return true
+	}
+	return false
+}
+
+// peek returns the next byte, failing at the end of data
+func (d *decoder) peek() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, d.syntaxError("unexpected end of data")
+	}
+	return d.data[d.pos], nil
+}
+
+// nest enters a list or dictionary
+func (d *decoder) nest() error {
+	if d.depth++; d.depth > maxDepth {
+		return d.syntaxError("exceeded max depth")
+	}
+	return nil
+}
+
+// value decodes the next value into v
+func (d *decoder) value(v reflect.Value, path string) error {
+	start := d.pos
+	c, err := d.peek()
+	if err != nil {
+		return err
+	}
+	u, v := indirect(v)
+	if u != nil {
+		if err := d.skip(); err != nil {
+			return err
+		}
+		return u.UnmarshalBencode(d.data[start:d.pos])
+	}
+
+	switch {
+	case c == 'i':
+		digits, err := d.integer()
+		if err != nil {
+			return err
+		}
+		return d.storeInt(digits, v, path, start)
+	case c >= '0' && c <= '9':
+		s, err := d.string()
+		if err != nil {
+			return err
+		}
+		return d.storeString(s, v, path, start)
+	case c == 'l':
+		return d.list(v, path)
+	case c == 'd':
+		return d.dict(v, path)
+	}
+	return d.syntaxError("invalid character " + strconv.QuoteRune(rune(c)))
+}
+
+// indirect allocates the pointers on the way to the value v points to, and
+// returns the first Unmarshaler found on the way
+func indirect(v reflect.Value) (Unmarshaler, reflect.Value) {
+	for {
+		if v.Kind() != reflect.Ptr && v.CanAddr() && reflect.PtrTo(v.Type()).Implements(unmarshalerType) {
+			return v.Addr().Interface().(Unmarshaler), v
+		}
+		if v.Kind() != reflect.Ptr {
+			return nil, v
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		if v.Type().Implements(unmarshalerType) {
+			return v.Interface().(Unmarshaler), v
+		}
+		v = v.Elem()
+	}
+}
+
+// isEmptyInterface reports an interface{} destination, which takes the
+// natural Go type of any value
+func isEmptyInterface(v reflect.Value) bool {
+	return v.Kind() == reflect.Interface && v.NumMethod() == 0
+}
+
+func (d *decoder) storeInt(digits []byte, v reflect.Value, path string, start int) error {
+	s := string(digits)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || v.OverflowInt(n) {
+			return d.decodeError("integer", v, path, start)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil || v.OverflowUint(n) {
+			return d.decodeError("integer", v, path, start)
+		}
+		v.SetUint(n)
+	case reflect.Bool:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return d.decodeError("integer", v, path, start)
+		}
+		v.SetBool(n != 0)
+	case reflect.Interface:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || !isEmptyInterface(v) {
+			return d.decodeError("integer", v, path, start)
+		}
+		v.Set(reflect.ValueOf(n))
+	default:
+		return d.decodeError("integer", v, path, start)
+	}
+	return nil
+}
+
+func (d *decoder) storeString(s []byte, v reflect.Value, path string, start int) error {
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(string(s))
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		v.SetBytes(append([]byte{}, s...))
+	case v.Kind() == reflect.Array && v.Type().Elem().Kind() == reflect.Uint8:
+		if v.Len() != len(s) {
+			return d.decodeError("string", v, path, start)
+		}
+		reflect.Copy(v, reflect.ValueOf(s))
+	case isEmptyInterface(v):
+		v.Set(reflect.ValueOf(string(s)))
+	default:
+		return d.decodeError("string", v, path, start)
+	}
+	return nil
+}
+
+func (d *decoder) list(v reflect.Value, path string) error {
+	start := d.pos
+	d.pos++
+	if err := d.nest(); err != nil {
+		return err
+	}
+	defer func() { d.depth-- }()
+
+	dst := v
+	switch {
+	case isEmptyInterface(v):
+		dst = reflect.ValueOf(&[]interface{}{}).Elem()
+	case v.Kind() == reflect.Slice:
+		dst = reflect.MakeSlice(v.Type(), 0, 0)
+	case v.Kind() == reflect.Array:
+	default:
+		return d.decodeError("list", v, path, start)
+	}
+
+	i := 0
+	for ; !d.consume('e'); i++ {
+		elemPath := path + "[" + strconv.Itoa(i) + "]"
+		switch {
+		case dst.Kind() == reflect.Slice:
+			dst = reflect.Append(dst, reflect.Zero(dst.Type().Elem()))
+			if err := d.value(dst.Index(i), elemPath); err != nil {
+				return err
+			}
+		case i < dst.Len():
+			if err := d.value(dst.Index(i), elemPath); err != nil {
+				return err
+			}
+		default: // past the end of the array
+			if err := d.skip(); err != nil {
+				return err
+			}
+		}
+	}
+	if dst.Kind() == reflect.Array {
+		for ; i < dst.Len(); i++ {
+			dst.Index(i).Set(reflect.Zero(dst.Type().Elem()))
+		}
+		return nil
+	}
+	v.Set(dst)
+	return nil
+}
+
+func (d *decoder) dict(v reflect.Value, path string) error {
+	start := d.pos
+	d.pos++
+	if err := d.nest(); err != nil {
+		return err
+	}
+	defer func() { d.depth-- }()
+
+	var fields []fieldInfo
+	dst := v
+	switch {
+	case isEmptyInterface(v):
+		dst = reflect.ValueOf(map[string]interface{}{})
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+	case v.Kind() == reflect.Struct:
+		fields = structFields(v.Type())
+	default:
+		return d.decodeError("dictionary", v, path, start)
+	}
+
+	for prev, first := "", true; !d.consume('e'); first = false {
+		key, err := d.key(prev, first)
+		if err != nil {
+			return err
+		}
+		prev = key
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+
+		if dst.Kind() == reflect.Map {
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := d.value(elem, keyPath); err != nil {
+				return err
+			}
+			dst.SetMapIndex(reflect.ValueOf(key).Convert(dst.Type().Key()), elem)
+			continue
+		}
+		field := -1
+		for _, f := range fields {
+			if f.name == key {
+				field = f.index
+				break
+			}
+		}
+		if field < 0 {
+			if err := d.skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := d.value(dst.Field(field), keyPath); err != nil {
+			return err
+		}
+	}
+	if isEmptyInterface(v) {
+		v.Set(dst)
+	}
+	return nil
+}
+
+// key reads a dictionary key; in strict mode, unless it is the first one, it
+// must sort after `prev`, the previous key
+func (d *decoder) key(prev string, first bool) (string, error) {
+	start := d.pos
+	c, err := d.peek()
+	if err != nil {
+		return "", err
+	}
+	if c < '0' || c > '9' {
+		return "", d.syntaxError("dictionary key is not a string")
+	}
+	key, err := d.string()
+	if err != nil {
+		return "", err
+	}
+	if d.strict && !first && string(key) <= prev {
+		d.pos = start
+		return "", d.syntaxError("dictionary keys not sorted")
+	}
+	return string(key), nil
+}
+
+// skip goes past the next value, checking it is well formed
+func (d *decoder) skip() error {
+	c, err := d.peek()
+	if err != nil {
+		return err
+	}
+	switch {
+	case c == 'i':
+		_, err := d.integer()
+		return err
+	case c >= '0' && c <= '9':
+		_, err := d.string()
+		return err
+	case c == 'l' || c == 'd':
+		d.pos++
+		if err := d.nest(); err != nil {
+			return err
+		}
+		defer func() { d.depth-- }()
+		for prev, first := "", true; !d.consume('e'); first = false {
+			if c == 'd' {
+				key, err := d.key(prev, first)
+				if err != nil {
+					return err
+				}
+				prev = key
+			}
+			if err := d.skip(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return d.syntaxError("invalid character " + strconv.QuoteRune(rune(c)))
+}
+
+// integer reads an integer, "i42e", and returns its digits
+func (d *decoder) integer() ([]byte, error) {
+	d.pos++ // 'i'
+	start := d.pos
+	d.consume('-')
+	if err := d.digits(); err != nil {
+		return nil, err
+	}
+	digits := d.data[start:d.pos]
+	if d.strict && (digits[0] == '-' && digits[1] == '0' || digits[0] == '0' && len(digits) > 1) {
+		d.pos = start
+		return nil, d.syntaxError("non-canonical integer")
+	}
+	if !d.consume('e') {
+		if d.pos >= len(d.data) {
+			return nil, d.syntaxError("unexpected end of data")
+		}
+		return nil, d.syntaxError("invalid character " + strconv.QuoteRune(rune(d.data[d.pos])) + " in integer")
+	}
+	return digits, nil
+}
+
+// string reads a string, "4:spam", and returns its contents
+func (d *decoder) string() ([]byte, error) {
+	start := d.pos
+	if err := d.digits(); err != nil {
+		return nil, err
+	}
+	digits := d.data[start:d.pos]
+	if d.strict && digits[0] == '0' && len(digits) > 1 {
+		d.pos = start
+		return nil, d.syntaxError("non-canonical string length")
+	}
+	if !d.consume(':') {
+		if d.pos >= len(d.data) {
+			return nil, d.syntaxError("unexpected end of data")
+		}
+		return nil, d.syntaxError("invalid character " + strconv.QuoteRune(rune(d.data[d.pos])) + " in string length")
+	}
+	n, err := strconv.ParseInt(string(digits), 10, 64)
+	if err != nil || n > int64(len(d.data)-d.pos) {
+		d.pos = start
+		return nil, d.syntaxError("string length out of range")
+	}
+	s := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return s, nil
+}
+
+// digits goes past one or more decimal digits
+func (d *decoder) digits() error {
+	start := d.pos
+	for d.pos < len(d.data) && d.data[d.pos] >= '0' && d.data[d.pos] <= '9' {
+		d.pos++
+	}
+	if d.pos == start {
+		if d.pos >= len(d.data) {
+			return d.syntaxError("unexpected end of data")
+		}
+		return d.syntaxError("expected digit, got " + strconv.QuoteRune(rune(d.data[d.pos])))
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package bencode
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode(t *testing.T) {
+	v, err := Decode([]byte("d4:infod6:lengthi42e4:name3:fooe4:listl1:ai-1eee"))
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"info": map[string]interface{}{"length": int64(42), "name": "foo"},
+		"list": []interface{}{"a", int64(-1)},
+	}, v)
+
+	for _, bad := range []string{"", "i42", "5:abc", "x", "i4e1", "l", "d1:ae"} {
+		_, err := Decode([]byte(bad))
+		require.Error(t, err, bad)
+	}
+}
+
+func TestRawValue(t *testing.T) {
+	raw, err := RawValue([]byte("d1:ai1e4:infod4:name3:fooe1:zle"), "info")
+	require.NoError(t, err)
+	require.Equal(t, "d4:name3:fooe", string(raw))
+
+	_, err = RawValue([]byte("d1:ai1ee"), "info")
+	require.Error(t, err)
+}
+
+type testFile struct {
+	Length int64    `bencode:"length"`
+	Path   []string `bencode:"path"`
+}
+
+type testInfo struct {
+	Name        string     `bencode:"name"`
+	PieceLength int        `bencode:"piece length"`
+	Pieces      []byte     `bencode:"pieces"`
+	Private     bool       `bencode:"private,omitempty"`
+	Files       []testFile `bencode:"files,omitempty"`
+}
+
+type testTorrent struct {
+	Announce     string            `bencode:"announce"`
+	AnnounceList [][]string        `bencode:"announce-list,omitempty"`
+	CreationDate *int64            `bencode:"creation date"`
+	Info         testInfo          `bencode:"info"`
+	Extra        map[string]string `bencode:"extra,omitempty"`
+	Ignored      string            `bencode:"-"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	data := "d8:announce3:url13:creation datei1571270400e7:unknowni1e" +
+		"4:infod5:filesld6:lengthi7e4:pathl1:a1:beee4:name3:foo12:piece lengthi16384e6:pieces4:\x00\x01\x02\x037:privatei1eee"
+	var torrent testTorrent
+	require.NoError(t, Unmarshal([]byte(data), &torrent))
+	date := int64(1571270400)
+	require.Equal(t, testTorrent{
+		Announce:     "url",
+		CreationDate: &date,
+		Info: testInfo{
+			Name:        "foo",
+			PieceLength: 16384,
+			Pieces:      []byte{0, 1, 2, 3},
+			Private:     true,
+			Files:       []testFile{{Length: 7, Path: []string{"a", "b"}}},
+		},
+	}, torrent)
+
+	var hash [4]byte
+	require.NoError(t, Unmarshal([]byte("4:\x01\x02\x03\x04"), &hash))
+	require.Equal(t, [4]byte{1, 2, 3, 4}, hash)
+	var m map[string]int
+	require.NoError(t, Unmarshal([]byte("d1:bi2e1:ai1e1:ai3ee"), &m), "lenient about order and duplicates")
+	require.Equal(t, map[string]int{"a": 3, "b": 2}, m)
+	var n int
+	require.NoError(t, Unmarshal([]byte("i007e"), &n))
+	require.Equal(t, 7, n)
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	var torrent testTorrent
+	err := Unmarshal([]byte("d4:infod12:piece length3:fooee"), &torrent)
+	var decodeErr *DecodeError
+	require.True(t, errors.As(err, &decodeErr), "got %v", err)
+	require.Equal(t, "info.piece length", decodeErr.Path)
+	require.Equal(t, "string", decodeErr.Value)
+	require.Equal(t, int64(23), decodeErr.Offset)
+
+	var small int8
+	require.True(t, errors.As(Unmarshal([]byte("i300e"), &small), &decodeErr))
+	var unsigned uint
+	require.True(t, errors.As(Unmarshal([]byte("i-1e"), &unsigned), &decodeErr))
+	var hash [20]byte
+	require.True(t, errors.As(Unmarshal([]byte("3:abc"), &hash), &decodeErr))
+
+	var v interface{}
+	for bad, offset := range map[string]int64{
+		"i12x3e":     3,
+		"ie":         1,
+		"d3:abci1e":  9,
+		"di1ei2ee":   1,
+		"99:abc":     0,
+		"l1:ae1:b":   5,
+		"li1e2:abce": 8,
+	} {
+		var syntaxErr *SyntaxError
+		require.True(t, errors.As(Unmarshal([]byte(bad), &v), &syntaxErr), bad)
+		require.Equal(t, offset, syntaxErr.Offset, bad)
+	}
+	require.Error(t, Unmarshal([]byte("i1e"), v), "not a pointer")
+}
+
+func TestUnmarshalStrict(t *testing.T) {
+	var v interface{}
+	require.NoError(t, UnmarshalStrict([]byte("d1:ai-1e1:bli0e0:ee"), &v))
+	for _, bad := range []string{"i007e", "i-0e", "03:abc", "d1:bi1e1:ai2ee", "d1:ai1e1:ai2ee", "ld1:bi1e1:ai2eee"} {
+		require.NoError(t, Unmarshal([]byte(bad), &v), bad)
+		var syntaxErr *SyntaxError
+		require.True(t, errors.As(UnmarshalStrict([]byte(bad), &v), &syntaxErr), bad)
+	}
+	var raw RawMessage
+	require.Error(t, UnmarshalStrict([]byte("d1:bi1e1:ai2ee"), &raw), "raw values are checked too")
+}
+
+func TestRawMessage(t *testing.T) {
+	var torrent struct {
+		Announce string     `bencode:"announce"`
+		Info     RawMessage `bencode:"info"`
+	}
+	data := []byte("d8:announce3:url4:infod4:name3:foo1:ai1eee")
+	require.NoError(t, Unmarshal(data, &torrent))
+	require.Equal(t, "d4:name3:foo1:ai1ee", string(torrent.Info))
+
+	raw, err := RawValue(data, "info")
+	require.NoError(t, err)
+	require.Equal(t, torrent.Info, RawMessage(raw))
+
+	out, err := Marshal(torrent)
+	require.NoError(t, err)
+	require.Equal(t, string(data), string(out), "the unsorted info dictionary is kept as is")
+}
@@ -0,0 +1,201 @@
+package bencode
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Marshal returns the encoding of v, see the package documentation for the
+// mapping of Go values. Dictionary keys are written sorted, so the result is
+// in canonical form. Nil pointers and interfaces, which have no bencode
+// representation, are left out of dictionaries and yield an
+// *UnsupportedTypeError anywhere else.
+func Marshal(v interface{}) ([]byte, error) {
+	e := &encoder{}
+	if err := e.value(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+type encoder struct {
+	buf   bytes.Buffer
+	depth int
+}
+
+func (e *encoder) value(v reflect.Value) error {
+	if !v.IsValid() {
+		return &UnsupportedTypeError{}
+	}
+
+	if v.Type().Implements(marshalerType) {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			return &UnsupportedTypeError{Type: v.Type()}
+		}
+		return e.marshaler(v.Interface().(Marshaler))
+	}
+	if v.Kind() != reflect.Ptr && v.CanAddr() && reflect.PtrTo(v.Type()).Implements(marshalerType) {
+		return e.marshaler(v.Addr().Interface().(Marshaler))
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf.WriteString("i1e")
+		} else {
+			e.buf.WriteString("i0e")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.buf.WriteByte('i')
+		e.buf.WriteString(strconv.FormatInt(v.Int(), 10))
+		e.buf.WriteByte('e')
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.buf.WriteByte('i')
+		e.buf.WriteString(strconv.FormatUint(v.Uint(), 10))
+		e.buf.WriteByte('e')
+	case reflect.String:
+		e.string(v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.bytes(byteSlice(v))
+			return nil
+		}
+		if err := e.nest(v); err != nil {
+			return err
+		}
+		defer func() { e.depth-- }()
+		e.buf.WriteByte('l')
+		for i := 0; i < v.Len(); i++ {
+			if err := e.value(v.Index(i)); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte('e')
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return &UnsupportedTypeError{Type: v.Type()}
+		}
+		if err := e.nest(v); err != nil {
+			return err
+		}
+		defer func() { e.depth-- }()
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		e.buf.WriteByte('d')
+		for _, key := range keys {
+			elem := v.MapIndex(key)
+			if isNil(elem) {
+				continue
+			}
+			e.string(key.String())
+			if err := e.value(elem); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte('e')
+	case reflect.Struct:
+		fields := structFields(v.Type())
+		sort.SliceStable(fields, func(i, j int) bool { return fields[i].name < fields[j].name })
+		if err := e.nest(v); err != nil {
+			return err
+		}
+		defer func() { e.depth-- }()
+		e.buf.WriteByte('d')
+		for i, f := range fields {
+			if i > 0 && f.name == fields[i-1].name {
+				return &UnsupportedTypeError{Type: v.Type()} // duplicate key
+			}
+			elem := v.Field(f.index)
+			if isNil(elem) || f.omitEmpty && isEmptyValue(elem) {
+				continue
+			}
+			e.string(f.name)
+			if err := e.value(elem); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte('e')
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return &UnsupportedTypeError{Type: v.Type()}
+		}
+		return e.value(v.Elem())
+	default:
+		return &UnsupportedTypeError{Type: v.Type()}
+	}
+	return nil
+}
+
+// nest enters a list or dictionary. Exceeding maxDepth most likely means v
+// holds a cycle.
+func (e *encoder) nest(v reflect.Value) error {
+	if e.depth++; e.depth > maxDepth {
+		return &UnsupportedTypeError{Type: v.Type()}
+	}
+	return nil
+}
+
+// marshaler writes the encoding returned by m, once checked
+func (e *encoder) marshaler(m Marshaler) error {
+	b, err := m.MarshalBencode()
+	if err != nil {
+		return err
+	}
+	if err := checkValid(b); err != nil {
+		return err
+	}
+	e.buf.Write(b)
+	return nil
+}
+
+func (e *encoder) string(s string) {
+	e.buf.WriteString(strconv.Itoa(len(s)))
+	e.buf.WriteByte(':')
+	e.buf.WriteString(s)
+}
+
+func (e *encoder) bytes(b []byte) {
+	e.buf.WriteString(strconv.Itoa(len(b)))
+	e.buf.WriteByte(':')
+	e.buf.Write(b)
+}
+
+// byteSlice returns the bytes of a []byte or a byte array
+func byteSlice(v reflect.Value) []byte {
+	if v.Kind() == reflect.Slice {
+		return v.Bytes()
+	}
+	b := make([]byte, v.Len())
+	reflect.Copy(reflect.ValueOf(b), v)
+	return b
+}
+
+// isNil reports values without a bencode representation, left out of
+// dictionaries: nil pointers and interfaces, and nil raw values
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Slice:
+		return v.IsNil() && v.Type().Implements(marshalerType)
+	}
+	return false
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
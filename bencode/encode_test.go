@@ -0,0 +1,82 @@
+package bencode
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type upper string
+
+func (u upper) MarshalBencode() ([]byte, error) {
+	return Marshal(string(u) + "!")
+}
+
+func TestMarshal(t *testing.T) {
+	date := int64(1571270400)
+	torrent := testTorrent{
+		Announce:     "url",
+		CreationDate: &date,
+		Info: testInfo{
+			Name:        "foo",
+			PieceLength: 16384,
+			Pieces:      []byte{0, 1, 2, 3},
+		},
+		Extra:   map[string]string{"z": "1", "a": "2"},
+		Ignored: "not encoded",
+	}
+	data, err := Marshal(torrent)
+	require.NoError(t, err)
+	require.Equal(t, "d8:announce3:url13:creation datei1571270400e5:extrad1:a1:21:z1:1e"+
+		"4:infod4:name3:foo12:piece lengthi16384e6:pieces4:\x00\x01\x02\x03ee", string(data))
+
+	var decoded testTorrent
+	require.NoError(t, UnmarshalStrict(data, &decoded), "Marshal is canonical")
+	torrent.Ignored = ""
+	require.Equal(t, torrent, decoded)
+
+	for v, want := range map[interface{}]string{
+		true:                   "i1e",
+		uint64(1 << 63):        "i9223372036854775808e",
+		int8(-5):               "i-5e",
+		[3]byte{'a', 'b', 'c'}: "3:abc",
+		upper("hi"):            "3:hi!",
+		&date:                  "i1571270400e",
+	} {
+		data, err := Marshal(v)
+		require.NoError(t, err)
+		require.Equal(t, want, string(data))
+	}
+	data, err = Marshal(map[string]interface{}{"list": []interface{}{1, "a"}, "nil": nil})
+	require.NoError(t, err)
+	require.Equal(t, "d4:listli1e1:aee", string(data))
+}
+
+func TestMarshalErrors(t *testing.T) {
+	for _, v := range []interface{}{
+		nil,
+		1.5,
+		map[int]string{1: "a"},
+		[]interface{}{nil},
+		struct {
+			A int `bencode:"x"`
+			B int `bencode:"x"`
+		}{},
+	} {
+		_, err := Marshal(v)
+		var unsupported *UnsupportedTypeError
+		require.True(t, errors.As(err, &unsupported), "%#v: %v", v, err)
+	}
+	_, err := Marshal(RawMessage("i1"))
+	var syntaxErr *SyntaxError
+	require.True(t, errors.As(err, &syntaxErr))
+
+	type cycle struct {
+		Next *cycle
+	}
+	c := &cycle{}
+	c.Next = c
+	_, err = Marshal(c)
+	require.Error(t, err)
+}
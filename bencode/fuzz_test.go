@@ -0,0 +1,75 @@
+package bencode
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func addSeeds(f *testing.F) {
+	for _, seed := range []string{
+		"i42e", "i-1e", "i007e", "0:", "4:spam", "le", "de", "li1e1:ae",
+		"d1:ai1e1:bl1:cee", "d1:bi1e1:ai2ee", "d1:ai1e1:ai2ee",
+		"d8:announce3:url4:infod6:lengthi7e4:name3:foo12:piece lengthi16384e6:pieces0:ee",
+	} {
+		f.Add([]byte(seed))
+	}
+}
+
+// FuzzUnmarshal checks that whatever decodes encodes back to the same value,
+// exactly so in strict mode, and that the Decoder agrees with Unmarshal
+func FuzzUnmarshal(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var v interface{}
+		if err := Unmarshal(data, &v); err != nil {
+			return
+		}
+		out, err := Marshal(v)
+		require.NoError(t, err)
+		var again interface{}
+		require.NoError(t, UnmarshalStrict(out, &again))
+		require.Equal(t, v, again)
+
+		var strict interface{}
+		if UnmarshalStrict(data, &strict) == nil {
+			require.Equal(t, string(data), string(out))
+		}
+
+		dec := NewDecoder(bytes.NewReader(data))
+		var streamed interface{}
+		require.NoError(t, dec.Decode(&streamed))
+		require.Equal(t, v, streamed)
+		require.Equal(t, io.EOF, dec.Decode(&streamed))
+
+		_, _ = RawValue(data, "info")
+	})
+}
+
+// FuzzUnmarshalStruct checks that decoding into structs and raw values
+// doesn't panic, and that their encoding is stable
+func FuzzUnmarshalStruct(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var torrent testTorrent
+		if err := Unmarshal(data, &torrent); err == nil {
+			out, err := Marshal(torrent)
+			require.NoError(t, err)
+			var again testTorrent
+			require.NoError(t, Unmarshal(out, &again))
+			out2, err := Marshal(again)
+			require.NoError(t, err)
+			require.Equal(t, string(out), string(out2))
+		}
+
+		var raw RawMessage
+		if err := Unmarshal(data, &raw); err == nil {
+			require.Equal(t, string(data), string(raw))
+			out, err := Marshal(raw)
+			require.NoError(t, err)
+			require.Equal(t, string(data), string(out))
+		}
+	})
+}
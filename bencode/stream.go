@@ -0,0 +1,140 @@
+package bencode
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+)
+
+// A Decoder reads and decodes bencoded values from a stream, one after the
+// other
+type Decoder struct {
+	r      *bufio.Reader
+	buf    bytes.Buffer
+	offset int64
+	strict bool
+}
+
+// NewDecoder returns a new decoder reading from r. It buffers its input, and
+// may read past the last value decoded.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Strict makes the decoder only accept the canonical encoding, see
+// UnmarshalStrict
+func (d *Decoder) Strict() {
+	d.strict = true
+}
+
+// InputOffset returns the position in the stream after the last value
+// decoded, in bytes
+func (d *Decoder) InputOffset() int64 {
+	return d.offset
+}
+
+// Decode reads the next value from the stream and stores it into the value
+// pointed to by v, see Unmarshal. It returns io.EOF once the stream ends
+// between two values, and io.ErrUnexpectedEOF if it ends within one.
+func (d *Decoder) Decode(v interface{}) error {
+	data, err := d.readValue()
+	if err != nil {
+		return err
+	}
+	offset := d.offset
+	d.offset += int64(len(data))
+	return unmarshal(data, v, d.strict, offset)
+}
+
+// readValue reads the encoding of the next value. It only finds where the
+// value ends, unmarshal checks the rest.
+func (d *Decoder) readValue() ([]byte, error) {
+	d.buf.Reset()
+	for depth := 0; ; {
+		c, err := d.r.ReadByte()
+		if err == io.EOF && d.buf.Len() == 0 {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		d.buf.WriteByte(c)
+		switch {
+		case c == 'i':
+			if _, err := d.readUntil('e'); err != nil {
+				return nil, err
+			}
+		case c >= '0' && c <= '9':
+			length, err := d.readUntil(':')
+			if err != nil {
+				return nil, err
+			}
+			n, err := strconv.ParseInt(string(c)+length, 10, 64)
+			if err != nil {
+				return nil, d.syntaxError("invalid string length")
+			}
+			if _, err := io.CopyN(&d.buf, d.r, n); err != nil {
+				return nil, unexpectedEOF(err)
+			}
+		case c == 'l' || c == 'd':
+			if depth++; depth > maxDepth {
+				return nil, d.syntaxError("exceeded max depth")
+			}
+		case c == 'e' && depth > 0:
+			depth--
+		default:
+			return nil, d.syntaxError("invalid character " + strconv.QuoteRune(rune(c)))
+		}
+		if depth == 0 {
+			return d.buf.Bytes(), nil
+		}
+	}
+}
+
+// readUntil reads up to `delim`, and returns what came before it
+func (d *Decoder) readUntil(delim byte) (string, error) {
+	start := d.buf.Len()
+	for {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			return "", unexpectedEOF(err)
+		}
+		d.buf.WriteByte(c)
+		if c == delim {
+			return string(d.buf.Bytes()[start : d.buf.Len()-1]), nil
+		}
+	}
+}
+
+// syntaxError reports an error about the last byte read
+func (d *Decoder) syntaxError(msg string) error {
+	return &SyntaxError{Msg: msg, Offset: d.offset + int64(d.buf.Len()-1)}
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// An Encoder writes bencoded values to a stream
+type Encoder struct {
+	w io.Writer
+}
+
+// NewEncoder returns a new encoder writing to w
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the encoding of v to the stream, see Marshal
+func (e *Encoder) Encode(v interface{}) error {
+	data, err := Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
@@ -0,0 +1,63 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecoder(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("i1e4:spamld1:ai1eeed4:name3:fooe"))
+	var n int
+	require.NoError(t, dec.Decode(&n))
+	require.Equal(t, 1, n)
+	var s string
+	require.NoError(t, dec.Decode(&s))
+	require.Equal(t, "spam", s)
+	var list []map[string]int
+	require.NoError(t, dec.Decode(&list))
+	require.Equal(t, []map[string]int{{"a": 1}}, list)
+	require.Equal(t, int64(19), dec.InputOffset())
+	var raw RawMessage
+	require.NoError(t, dec.Decode(&raw))
+	require.Equal(t, "d4:name3:fooe", string(raw))
+	require.Equal(t, io.EOF, dec.Decode(&raw))
+
+	for input, want := range map[string]error{
+		"l1:a":         io.ErrUnexpectedEOF,
+		"5:ab":         io.ErrUnexpectedEOF,
+		"i12":          io.ErrUnexpectedEOF,
+		"e":            &SyntaxError{Msg: "invalid character 'e'", Offset: 0},
+		"i1ex":         &SyntaxError{Msg: "invalid character 'x'", Offset: 3},
+		"3x:abc":       &SyntaxError{Msg: "invalid string length", Offset: 2},
+		"di1ei2ee":     &SyntaxError{Msg: "dictionary key is not a string", Offset: 1},
+		"i1e3:a":       io.ErrUnexpectedEOF,
+		"i1ed1:bi1e1:": io.ErrUnexpectedEOF,
+	} {
+		dec := NewDecoder(strings.NewReader(input))
+		var err error
+		var v interface{}
+		for err == nil {
+			err = dec.Decode(&v)
+		}
+		require.Equal(t, want, err, input)
+	}
+
+	dec = NewDecoder(strings.NewReader("d1:bi1e1:ai2ee"))
+	dec.Strict()
+	var syntaxErr *SyntaxError
+	require.True(t, errors.As(dec.Decode(&raw), &syntaxErr))
+}
+
+func TestEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	require.NoError(t, enc.Encode(1))
+	require.NoError(t, enc.Encode(map[string]string{"a": "b"}))
+	require.Error(t, enc.Encode(1.5))
+	require.Equal(t, "i1ed1:a1:be", buf.String())
+}
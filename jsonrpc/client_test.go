@@ -0,0 +1,114 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/tab1293/go-rtorrent/xmlrpc"
+)
+
+func TestClient(t *testing.T) {
+	s := xmlrpc.NewServer()
+	s.Register("echo", func(args ...interface{}) (interface{}, error) {
+		return args, nil
+	})
+	s.Register("fail", func(args ...interface{}) (interface{}, error) {
+		return nil, errors.Wrap(&xmlrpc.Fault{Code: -501, Message: "Could not find info-hash."}, "d.start")
+	})
+	srv := httptest.NewServer(Handler(s.Call))
+	defer srv.Close()
+	client := NewClient(SchemePrefix+srv.URL, false)
+
+	t.Run("call", func(t *testing.T) {
+		result, err := client.Call("echo", "a", 1, int64(1)<<40, 1.5, map[string]interface{}{"k": []interface{}{2}})
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{
+			"a", int64(1), int64(1) << 40, 1.5, map[string]interface{}{"k": []interface{}{int64(2)}},
+		}, result)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := client.Call("fail")
+		require.Equal(t, &Error{Code: -501, Message: "Could not find info-hash."}, err)
+
+		_, err = client.Call("bogus")
+		require.Equal(t, xmlrpc.FaultMethodNotFound, err.(*Error).Code)
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		results, err := client.NewBatch().Send()
+		require.NoError(t, err)
+		require.Empty(t, results)
+
+		results, err = client.NewBatch().
+			Add("echo", "x").
+			Add("fail").
+			Add("echo").
+			Send()
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		require.Equal(t, []interface{}{"x"}, results[0].Value)
+		require.Equal(t, -501, results[1].Error.Code)
+		require.Equal(t, []interface{}{}, results[2].Value)
+	})
+}
+
+func TestBatchOrder(t *testing.T) {
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}))
+	defer srv.Close()
+	client := NewClient(srv.URL, false)
+
+	body = `[{"jsonrpc":"2.0","result":"second","id":2},{"jsonrpc":"2.0","result":"first","id":1}]`
+	results, err := client.NewBatch().Add("a").Add("b").Send()
+	require.NoError(t, err)
+	require.Equal(t, "first", results[0].Value)
+	require.Equal(t, "second", results[1].Value)
+
+	body = `[{"jsonrpc":"2.0","result":"first","id":1}]`
+	_, err = client.NewBatch().Add("a").Add("b").Send()
+	require.Error(t, err)
+
+	body = `[{"jsonrpc":"2.0","result":"first","id":1},{"jsonrpc":"2.0","result":"again","id":1}]`
+	_, err = client.NewBatch().Add("a").Add("b").Send()
+	require.Error(t, err)
+}
+
+func TestHandlerErrors(t *testing.T) {
+	h := Handler(func(method string, params ...interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	for body, code := range map[string]int{
+		`{"jsonrpc":`:              CodeParseError,
+		`{"jsonrpc":"2.0","id":1}`: CodeInvalidRequest,
+		`{"jsonrpc":"2.0","method":"m","params":1,"id":1}`: CodeInvalidRequest,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		var resp response
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp), body)
+		require.NotNil(t, resp.Error, body)
+		require.Equal(t, code, resp.Error.Code, body)
+	}
+
+	// successful calls always carry a result, null included
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc":"2.0","method":"m","id":1}`))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	require.JSONEq(t, `{"jsonrpc":"2.0","result":null,"id":1}`, w.Body.String())
+
+	// notifications get no response
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc":"2.0","method":"m"}`))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNoContent, w.Code)
+}
@@ -0,0 +1,163 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/tab1293/go-rtorrent/xmlrpc"
+)
+
+// Standard JSON-RPC 2.0 error codes
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Handler answers JSON-RPC requests, single or batched, by calling the
+// function with the method name and params, e.g. the Call method of an
+// xmlrpc.Server to serve the same methods over both protocols:
+//
+//	http.Handle("/RPC2", jsonrpc.Handler(server.Call))
+//
+// An *Error or *xmlrpc.Fault returned by the function is sent with its code,
+// any other error as CodeInternalError.
+type Handler func(method string, params ...interface{}) (interface{}, error)
+
+type serverRequest struct {
+	Version string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type serverResponse struct {
+	Version string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result"`
+	Error   *Error          `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// MarshalJSON writes either "result", null included, or "error": JSON-RPC
+// 2.0 requires exactly one of them
+func (r serverResponse) MarshalJSON() ([]byte, error) {
+	if r.Error != nil {
+		return json.Marshal(struct {
+			Version string          `json:"jsonrpc"`
+			Error   *Error          `json:"error"`
+			ID      json.RawMessage `json:"id"`
+		}{r.Version, r.Error, r.ID})
+	}
+	type plain serverResponse
+	return json.Marshal(plain(r))
+}
+
+// ServeHTTP answers a single request or a batch
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "JSON-RPC requests must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var out interface{}
+	requests, batch, err := readRequests(r.Body)
+	switch {
+	case err != nil:
+		out = serverResponse{Version: "2.0", Error: &Error{Code: CodeParseError, Message: err.Error()}, ID: json.RawMessage("null")}
+	case batch:
+		responses := []serverResponse{}
+		for _, req := range requests {
+			if resp, ok := h.serve(req); ok {
+				responses = append(responses, resp)
+			}
+		}
+		out = responses
+	default:
+		resp, ok := h.serve(requests[0])
+		if !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		out = resp
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buf).Encode(out); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(buf.Bytes())
+}
+
+// serve runs a single request, returning false for notifications, which get
+// no response
+func (h Handler) serve(raw json.RawMessage) (serverResponse, bool) {
+	var req serverRequest
+	resp := serverResponse{Version: "2.0", ID: json.RawMessage("null")}
+	if err := json.Unmarshal(raw, &req); err != nil || req.Method == "" {
+		resp.Error = &Error{Code: CodeInvalidRequest, Message: "Invalid Request"}
+		return resp, true
+	}
+	if len(req.ID) == 0 {
+		h.call(req)
+		return resp, false
+	}
+	resp.ID = req.ID
+	resp.Result, resp.Error = h.call(req)
+	return resp, true
+}
+
+func (h Handler) call(req serverRequest) (interface{}, *Error) {
+	var params []interface{}
+	if len(req.Params) > 0 {
+		v, err := decodeResult(req.Params)
+		if err != nil {
+			return nil, &Error{Code: CodeInvalidRequest, Message: err.Error()}
+		}
+		if params, _ = v.([]interface{}); params == nil && v != nil {
+			return nil, &Error{Code: CodeInvalidRequest, Message: "params must be an array"}
+		}
+	}
+	result, err := h(req.Method, params...)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return result, nil
+}
+
+// toError converts err into an *Error, keeping the code of faults
+func toError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	var f *xmlrpc.Fault
+	if errors.As(err, &f) {
+		return &Error{Code: f.Code, Message: f.Message}
+	}
+	return &Error{Code: CodeInternalError, Message: err.Error()}
+}
+
+// readRequests reads a single request or a batch, reporting whether it was a batch
+func readRequests(r io.Reader) ([]json.RawMessage, bool, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, false, err
+	}
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(raw, &batch); err != nil {
+			return nil, false, err
+		}
+		return batch, true, nil
+	}
+	return []json.RawMessage{raw}, false, nil
+}
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/tab1293/go-rtorrent/metainfo"
 	"github.com/tab1293/go-rtorrent/rtorrent"
 	"github.com/urfave/cli"
 )
@@ -114,7 +116,7 @@ func initApp() *cli.App {
 		},
 	}, {
 		Name:   "add-torrent",
-		Usage:  "add torrent from file",
+		Usage:  "add torrent from file, printing its name, hash, size and trackers",
 		Action: addTorrentFile,
 		Flags: []cli.Flag{
 			cli.StringFlag{
@@ -309,14 +311,51 @@ func addTorrentFile(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	err = conn.AddTorrent(b)
+	meta, err := metainfo.Parse(b)
 	if err != nil {
-		return errors.Wrap(err, "failed to start torrent")
+		return errors.Wrap(err, "invalid torrent file")
+	}
+	printMetaInfo(meta)
+	_, err = conn.AddMetaInfo(meta, rtorrent.AddOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to add torrent")
 	}
 
 	return nil
 }
 
+// printMetaInfo prints a summary of a .torrent file
+func printMetaInfo(meta *metainfo.MetaInfo) {
+	info := meta.Info
+	files := len(info.Files)
+	if files == 0 {
+		files = 1
+	}
+	fmt.Printf("Name:      %s\n", info.Name)
+	fmt.Printf("Hash:      %s\n", meta.InfoHash())
+	if info.IsV2() {
+		fmt.Printf("Hash (v2): %x\n", meta.HashV2())
+	}
+	fmt.Printf("Size:      %d bytes in %d file(s)\n", info.TotalLength(), files)
+	fmt.Printf("Pieces:    %d x %d bytes\n", info.NumPieces(), info.PieceLength)
+	fmt.Printf("Private:   %t\n", info.Private)
+	if !meta.CreationDate.IsZero() {
+		fmt.Printf("Created:   %s\n", meta.CreationDate.Format(time.RFC3339))
+	}
+	if meta.CreatedBy != "" {
+		fmt.Printf("Tool:      %s\n", meta.CreatedBy)
+	}
+	if meta.Comment != "" {
+		fmt.Printf("Comment:   %s\n", meta.Comment)
+	}
+	for _, url := range meta.Trackers() {
+		fmt.Printf("Tracker:   %s\n", url)
+	}
+	for _, url := range meta.WebSeeds {
+		fmt.Printf("Web seed:  %s\n", url)
+	}
+}
+
 func startTorrent(c *cli.Context) error {
 	err := conn.StartTorrent(rtorrent.Torrent{Hash: hash})
 	if err != nil {
@@ -395,16 +434,9 @@ func setFilePriority(c *cli.Context) error {
 }
 
 func skipAllFiles(c *cli.Context) error {
-	files, err := conn.GetFiles(rtorrent.Torrent{Hash: hash})
+	err := conn.SetAllFilePriorities(rtorrent.Torrent{Hash: hash}, 0)
 	if err != nil {
-		return errors.Wrap(err, "failed to get files")
-	}
-	for i := range files {
-		err := conn.SetFilePrority(rtorrent.Torrent{Hash: hash}, i, 0)
-		if err != nil {
-			return errors.Wrap(err, "failed to list methods")
-		}
+		return errors.Wrap(err, "failed to skip files")
 	}
 	return nil
-
 }
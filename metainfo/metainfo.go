@@ -0,0 +1,338 @@
+// Package metainfo parses .torrent files, as defined by BEP 3 and, for v2 and
+// hybrid torrents, BEP 52, and computes their info-hashes.
+//
+//	meta, err := metainfo.Parse(data)
+//	fmt.Println(meta.Info.Name, meta.InfoHash())
+package metainfo
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tab1293/go-rtorrent/bencode"
+)
+
+// MetaInfo is the content of a .torrent file
+type MetaInfo struct {
+	// Announce is the URL of the tracker
+	Announce string
+	// AnnounceList are the tiers of tracker URLs (BEP 12), used instead of
+	// Announce if set
+	AnnounceList [][]string
+	// CreationDate is zero if unset
+	CreationDate time.Time
+	Comment      string
+	CreatedBy    string
+	// WebSeeds are the URLs of HTTP seeds (BEP 19)
+	WebSeeds []string
+	Info     Info
+	// InfoBytes is the info dictionary exactly as encoded in the file, the
+	// info-hashes are computed from it
+	InfoBytes []byte
+	// Raw is the whole file, as given to Parse
+	Raw []byte
+}
+
+// Info is the info dictionary of a torrent, which describes its content
+type Info struct {
+	// Name is the name of the file, or of the directory holding the files
+	Name        string
+	PieceLength int64
+	// Pieces are the concatenated SHA-1 hashes of the pieces, empty for v2
+	// only torrents
+	Pieces  []byte
+	Private bool
+	// Length is the size of single-file torrents
+	Length int64
+	// Files are the files of multi-file torrents, nil for single-file ones
+	Files []File
+	// MetaVersion is 2 for v2 and hybrid torrents, 1 otherwise
+	MetaVersion int
+}
+
+// File is a file of a multi-file torrent
+type File struct {
+	// Path is the path of the file within the torrent directory
+	Path   []string
+	Length int64
+}
+
+// PathString returns the path of the file within the torrent directory, "/"
+// separated
+func (f File) PathString() string {
+	return strings.Join(f.Path, "/")
+}
+
+// rawMetaInfo and rawInfo are the encodings of MetaInfo and Info. The keys
+// outside of the info dictionary are optional, and decoded one by one.
+type rawMetaInfo struct {
+	Announce     bencode.RawMessage `bencode:"announce"`
+	AnnounceList bencode.RawMessage `bencode:"announce-list"`
+	CreationDate bencode.RawMessage `bencode:"creation date"`
+	Comment      bencode.RawMessage `bencode:"comment"`
+	CreatedBy    bencode.RawMessage `bencode:"created by"`
+	URLList      bencode.RawMessage `bencode:"url-list"`
+	Info         bencode.RawMessage `bencode:"info"`
+}
+
+type rawInfo struct {
+	Name        string                 `bencode:"name"`
+	PieceLength int64                  `bencode:"piece length"`
+	Pieces      []byte                 `bencode:"pieces"`
+	Private     bool                   `bencode:"private"`
+	Length      *int64                 `bencode:"length"`
+	Files       []rawFile              `bencode:"files"`
+	MetaVersion int                    `bencode:"meta version"`
+	FileTree    map[string]interface{} `bencode:"file tree"`
+}
+
+type rawFile struct {
+	Length *int64   `bencode:"length"`
+	Path   []string `bencode:"path"`
+}
+
+// Parse parses the .torrent `data` and validates its info dictionary. The
+// other keys are optional: those of an unexpected type, such as a creation
+// date written as a string, are ignored as BitTorrent clients do.
+func Parse(data []byte) (*MetaInfo, error) {
+	var raw rawMetaInfo
+	if err := bencode.Unmarshal(data, &raw); err != nil {
+		return nil, errors.Wrap(err, "metainfo")
+	}
+	if raw.Info == nil {
+		return nil, errors.New("metainfo: no info dictionary")
+	}
+	m := &MetaInfo{InfoBytes: raw.Info, Raw: data}
+	optional(raw.Announce, &m.Announce)
+	optional(raw.Comment, &m.Comment)
+	optional(raw.CreatedBy, &m.CreatedBy)
+	var announceList [][]string
+	if optional(raw.AnnounceList, &announceList) {
+		m.AnnounceList = announceList
+	}
+	var created int64
+	if optional(raw.CreationDate, &created) && created != 0 {
+		m.CreationDate = time.Unix(created, 0)
+	}
+	// url-list is a single URL or a list of them
+	var url string
+	var urls []string
+	switch {
+	case optional(raw.URLList, &url) && url != "":
+		m.WebSeeds = []string{url}
+	case optional(raw.URLList, &urls):
+		m.WebSeeds = urls
+	}
+	info, err := parseInfo(raw.Info)
+	if err != nil {
+		return nil, err
+	}
+	m.Info = *info
+	return m, nil
+}
+
+// optional decodes the optional key `raw` into v, and reports whether it was
+// present and of the expected type
+func optional(raw bencode.RawMessage, v interface{}) bool {
+	return raw != nil && bencode.Unmarshal(raw, v) == nil
+}
+
+func parseInfo(data []byte) (*Info, error) {
+	var raw rawInfo
+	if err := bencode.Unmarshal(data, &raw); err != nil {
+		return nil, errors.Wrap(err, "metainfo: invalid info dictionary")
+	}
+	info := &Info{
+		Name:        raw.Name,
+		PieceLength: raw.PieceLength,
+		Pieces:      raw.Pieces,
+		Private:     raw.Private,
+		MetaVersion: 1,
+	}
+	if info.Name == "" {
+		return nil, errors.New("metainfo: info has no name")
+	}
+	if err := checkPath([]string{info.Name}); err != nil {
+		return nil, err
+	}
+	if info.PieceLength <= 0 {
+		return nil, errors.New("metainfo: info has no piece length")
+	}
+	switch raw.MetaVersion {
+	case 0, 1:
+	case 2:
+		info.MetaVersion = 2
+		if raw.FileTree == nil {
+			return nil, errors.New("metainfo: v2 info has no file tree")
+		}
+		if info.PieceLength < 16<<10 || info.PieceLength&(info.PieceLength-1) != 0 {
+			return nil, errors.Errorf("metainfo: invalid v2 piece length %d", info.PieceLength)
+		}
+	default:
+		return nil, errors.Errorf("metainfo: unsupported meta version %d", raw.MetaVersion)
+	}
+
+	switch {
+	case raw.Length != nil && raw.Files != nil:
+		return nil, errors.New("metainfo: info has both length and files")
+	case raw.Length != nil:
+		info.Length = *raw.Length
+		if info.Length < 0 {
+			return nil, errors.New("metainfo: negative length")
+		}
+	case raw.Files != nil:
+		if len(raw.Files) == 0 {
+			return nil, errors.New("metainfo: info has no files")
+		}
+		for _, f := range raw.Files {
+			if f.Length == nil || *f.Length < 0 {
+				return nil, errors.Errorf("metainfo: invalid length for file %q", strings.Join(f.Path, "/"))
+			}
+			info.Files = append(info.Files, File{Path: f.Path, Length: *f.Length})
+		}
+	case info.MetaVersion == 2:
+		files, err := walkFileTree(raw.FileTree, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 1 && len(files[0].Path) == 1 && files[0].Path[0] == info.Name {
+			info.Length = files[0].Length
+		} else {
+			info.Files = files
+		}
+	default:
+		return nil, errors.New("metainfo: info has neither length nor files")
+	}
+	for _, f := range info.Files {
+		if err := checkPath(f.Path); err != nil {
+			return nil, err
+		}
+	}
+
+	if raw.Pieces != nil || info.MetaVersion == 1 {
+		if len(info.Pieces)%sha1.Size != 0 {
+			return nil, errors.New("metainfo: pieces isn't a list of SHA-1 hashes")
+		}
+		if n := int64(len(info.Pieces) / sha1.Size); n != info.numPieces() {
+			return nil, errors.Errorf("metainfo: %d pieces for %d bytes of %d byte pieces", n, info.TotalLength(), info.PieceLength)
+		}
+	}
+	return info, nil
+}
+
+// walkFileTree lists the files of a v2 file tree, in path order. Files are
+// dictionaries with a single "" key holding their length.
+func walkFileTree(tree map[string]interface{}, dir []string) ([]File, error) {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var files []File
+	for _, name := range names {
+		node, ok := tree[name].(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("metainfo: invalid file tree entry %q", name)
+		}
+		path := append(append([]string(nil), dir...), name)
+		if name == "" {
+			if len(dir) == 0 {
+				return nil, errors.New("metainfo: file tree has an unnamed file")
+			}
+			length, ok := node["length"].(int64)
+			if !ok || length < 0 {
+				return nil, errors.Errorf("metainfo: invalid length for file %q", strings.Join(dir, "/"))
+			}
+			files = append(files, File{Path: dir, Length: length})
+			continue
+		}
+		sub, err := walkFileTree(node, path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, sub...)
+	}
+	if len(files) == 0 {
+		return nil, errors.Errorf("metainfo: empty directory %q in file tree", strings.Join(dir, "/"))
+	}
+	return files, nil
+}
+
+// checkPath rejects paths that would escape the torrent directory
+func checkPath(path []string) error {
+	if len(path) == 0 {
+		return errors.New("metainfo: file with an empty path")
+	}
+	for _, elem := range path {
+		if elem == "" || elem == "." || elem == ".." || strings.ContainsAny(elem, "/\x00") {
+			return errors.Errorf("metainfo: invalid file path %q", strings.Join(path, "/"))
+		}
+	}
+	return nil
+}
+
+// TotalLength returns the size of the content of the torrent
+func (i *Info) TotalLength() int64 {
+	if i.Files == nil {
+		return i.Length
+	}
+	var total int64
+	for _, f := range i.Files {
+		total += f.Length
+	}
+	return total
+}
+
+// NumPieces returns the number of pieces of the torrent
+func (i *Info) NumPieces() int {
+	return int(i.numPieces())
+}
+
+func (i *Info) numPieces() int64 {
+	return (i.TotalLength() + i.PieceLength - 1) / i.PieceLength
+}
+
+// IsV1 reports torrents with v1 metadata, v1 only or hybrid
+func (i *Info) IsV1() bool {
+	return i.MetaVersion == 1 || i.Pieces != nil
+}
+
+// IsV2 reports torrents with v2 metadata, v2 only or hybrid
+func (i *Info) IsV2() bool {
+	return i.MetaVersion == 2
+}
+
+// HashV1 returns the v1 info-hash, the SHA-1 of the info dictionary
+func (m *MetaInfo) HashV1() [sha1.Size]byte {
+	return sha1.Sum(m.InfoBytes)
+}
+
+// HashV2 returns the v2 info-hash, the SHA-256 of the info dictionary. It is
+// only meaningful for v2 and hybrid torrents.
+func (m *MetaInfo) HashV2() [sha256.Size]byte {
+	return sha256.Sum256(m.InfoBytes)
+}
+
+// InfoHash returns the v1 info-hash as rTorrent reports it, in upper case hex
+func (m *MetaInfo) InfoHash() string {
+	return fmt.Sprintf("%X", m.HashV1())
+}
+
+// Trackers returns the tracker URLs, from AnnounceList if set, from Announce
+// otherwise
+func (m *MetaInfo) Trackers() []string {
+	var urls []string
+	for _, tier := range m.AnnounceList {
+		urls = append(urls, tier...)
+	}
+	if len(urls) == 0 && m.Announce != "" {
+		urls = []string{m.Announce}
+	}
+	return urls
+}
@@ -0,0 +1,184 @@
+package metainfo
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tab1293/go-rtorrent/bencode"
+)
+
+func TestParse(t *testing.T) {
+	data, err := ioutil.ReadFile("../rtorrent/testdata/ubuntu-19.04-live-server-amd64.iso.torrent")
+	require.NoError(t, err)
+	meta, err := Parse(data)
+	require.NoError(t, err)
+
+	require.Equal(t, data, meta.Raw)
+	require.Equal(t, "http://torrent.ubuntu.com:6969/announce", meta.Announce)
+	require.Equal(t, [][]string{
+		{"http://torrent.ubuntu.com:6969/announce"},
+		{"http://ipv6.torrent.ubuntu.com:6969/announce"},
+	}, meta.AnnounceList)
+	require.Equal(t, []string{"http://torrent.ubuntu.com:6969/announce", "http://ipv6.torrent.ubuntu.com:6969/announce"}, meta.Trackers())
+	require.Equal(t, time.Unix(1555564384, 0), meta.CreationDate)
+	require.Equal(t, "Ubuntu CD releases.ubuntu.com", meta.Comment)
+
+	require.Equal(t, "ubuntu-19.04-live-server-amd64.iso", meta.Info.Name)
+	require.Equal(t, int64(524288), meta.Info.PieceLength)
+	require.Equal(t, int64(784334848), meta.Info.TotalLength())
+	require.Equal(t, 1496, meta.Info.NumPieces())
+	require.Nil(t, meta.Info.Files)
+	require.False(t, meta.Info.Private)
+	require.True(t, meta.Info.IsV1())
+	require.False(t, meta.Info.IsV2())
+
+	require.Equal(t, "B7B0FBAB74A85D4AC170662C645982A862826455", meta.InfoHash())
+	require.Equal(t, "e2e5fe0ddc63719fd12fafde5e62756f25442e6a8a4cd19251789e3791883d19", fmt.Sprintf("%x", meta.HashV2()))
+}
+
+// torrent encodes a .torrent with the given info dictionary
+func torrent(t *testing.T, info map[string]interface{}, extra map[string]interface{}) []byte {
+	meta := map[string]interface{}{"announce": "http://tracker/announce", "info": info}
+	for k, v := range extra {
+		meta[k] = v
+	}
+	data, err := bencode.Marshal(meta)
+	require.NoError(t, err)
+	return data
+}
+
+func multiFileInfo() map[string]interface{} {
+	return map[string]interface{}{
+		"name":         "album",
+		"piece length": 8,
+		"pieces":       strings.Repeat("x", 2*sha1.Size),
+		"private":      1,
+		"files": []interface{}{
+			map[string]interface{}{"length": 10, "path": []string{"cd1", "a.flac"}},
+			map[string]interface{}{"length": 6, "path": []string{"cover.jpg"}},
+		},
+	}
+}
+
+func TestParseMultiFile(t *testing.T) {
+	meta, err := Parse(torrent(t, multiFileInfo(), map[string]interface{}{
+		"url-list":   "http://seed/album",
+		"created by": "mktorrent 1.1",
+	}))
+	require.NoError(t, err)
+	require.Equal(t, []File{
+		{Path: []string{"cd1", "a.flac"}, Length: 10},
+		{Path: []string{"cover.jpg"}, Length: 6},
+	}, meta.Info.Files)
+	require.Equal(t, "cd1/a.flac", meta.Info.Files[0].PathString())
+	require.Equal(t, int64(16), meta.Info.TotalLength())
+	require.Equal(t, 2, meta.Info.NumPieces())
+	require.True(t, meta.Info.Private)
+	require.Equal(t, []string{"http://seed/album"}, meta.WebSeeds)
+	require.Equal(t, []string{"http://tracker/announce"}, meta.Trackers())
+	require.Equal(t, "mktorrent 1.1", meta.CreatedBy)
+	require.True(t, meta.CreationDate.IsZero())
+
+	raw, err := bencode.RawValue(torrent(t, multiFileInfo(), nil), "info")
+	require.NoError(t, err)
+	require.Equal(t, sha1.Sum(raw), meta.HashV1())
+}
+
+func TestParseV2(t *testing.T) {
+	root := strings.Repeat("r", 32)
+	info := map[string]interface{}{
+		"name":         "album",
+		"piece length": 16384,
+		"meta version": 2,
+		"file tree": map[string]interface{}{
+			"cover.jpg": map[string]interface{}{"": map[string]interface{}{"length": 6, "pieces root": root}},
+			"cd1": map[string]interface{}{
+				"a.flac": map[string]interface{}{"": map[string]interface{}{"length": 10, "pieces root": root}},
+			},
+		},
+	}
+	meta, err := Parse(torrent(t, info, nil))
+	require.NoError(t, err)
+	require.True(t, meta.Info.IsV2())
+	require.False(t, meta.Info.IsV1())
+	require.Equal(t, []File{
+		{Path: []string{"cd1", "a.flac"}, Length: 10},
+		{Path: []string{"cover.jpg"}, Length: 6},
+	}, meta.Info.Files)
+	require.Equal(t, 1, meta.Info.NumPieces())
+
+	info["file tree"] = map[string]interface{}{
+		"album": map[string]interface{}{"": map[string]interface{}{"length": 100000, "pieces root": root}},
+	}
+	meta, err = Parse(torrent(t, info, nil))
+	require.NoError(t, err)
+	require.Nil(t, meta.Info.Files)
+	require.Equal(t, int64(100000), meta.Info.Length)
+
+	info["length"] = 100000
+	info["pieces"] = strings.Repeat("x", 7*sha1.Size)
+	meta, err = Parse(torrent(t, info, nil))
+	require.NoError(t, err, "hybrid")
+	require.True(t, meta.Info.IsV1())
+	require.True(t, meta.Info.IsV2())
+}
+
+func TestParseLenient(t *testing.T) {
+	meta, err := Parse(torrent(t, multiFileInfo(), map[string]interface{}{
+		"announce":      1,
+		"announce-list": []string{"http://a/announce", "http://b/announce"},
+		"creation date": "2019-04-18",
+		"comment":       []string{"not", "a", "string"},
+		"url-list":      map[string]interface{}{"url": "http://seed"},
+	}))
+	require.NoError(t, err, "optional keys of another type are ignored")
+	require.Empty(t, meta.Announce)
+	require.Nil(t, meta.AnnounceList)
+	require.True(t, meta.CreationDate.IsZero())
+	require.Empty(t, meta.Comment)
+	require.Nil(t, meta.WebSeeds)
+	require.Equal(t, "album", meta.Info.Name)
+
+	meta, err = Parse(torrent(t, multiFileInfo(), map[string]interface{}{
+		"url-list": []string{"http://seed/1", "http://seed/2"},
+	}))
+	require.NoError(t, err)
+	require.Equal(t, []string{"http://seed/1", "http://seed/2"}, meta.WebSeeds)
+}
+
+func TestParseErrors(t *testing.T) {
+	for name, change := range map[string]func(info map[string]interface{}){
+		"no name":           func(info map[string]interface{}) { delete(info, "name") },
+		"parent name":       func(info map[string]interface{}) { info["name"] = ".." },
+		"no piece length":   func(info map[string]interface{}) { delete(info, "piece length") },
+		"truncated pieces":  func(info map[string]interface{}) { info["pieces"] = strings.Repeat("x", 30) },
+		"missing pieces":    func(info map[string]interface{}) { info["pieces"] = strings.Repeat("x", sha1.Size) },
+		"no files":          func(info map[string]interface{}) { delete(info, "files") },
+		"empty files":       func(info map[string]interface{}) { info["files"] = []interface{}{} },
+		"length and files":  func(info map[string]interface{}) { info["length"] = 16 },
+		"meta version":      func(info map[string]interface{}) { info["meta version"] = 3 },
+		"v2 without a tree": func(info map[string]interface{}) { info["meta version"] = 2 },
+		"wrong type":        func(info map[string]interface{}) { info["piece length"] = "8" },
+		"negative file size": func(info map[string]interface{}) {
+			info["files"].([]interface{})[1].(map[string]interface{})["length"] = -6
+		},
+		"escaping path": func(info map[string]interface{}) {
+			info["files"].([]interface{})[1].(map[string]interface{})["path"] = []string{"..", "etc", "passwd"}
+		},
+	} {
+		info := multiFileInfo()
+		change(info)
+		_, err := Parse(torrent(t, info, nil))
+		require.Error(t, err, name)
+	}
+
+	for _, data := range []string{"", "not bencode", "le", "d8:announce3:urle", "d4:infoi1ee"} {
+		_, err := Parse([]byte(data))
+		require.Error(t, err, data)
+	}
+}
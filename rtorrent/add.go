@@ -0,0 +1,183 @@
+package rtorrent
+
+import (
+	"context"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tab1293/go-rtorrent/metainfo"
+)
+
+// AddOptions configure a torrent being added. They are sent as post-load
+// commands, applied by rTorrent before the torrent is started or saved to
+// the session.
+type AddOptions struct {
+	// Start starts the torrent right away, with load.raw_start or load.start
+	Start bool
+	// Paused adds the torrent open but stopped, overriding Start: it is ready
+	// to be started with StartTorrent
+	Paused bool
+	// Directory is where the data is stored, directory.default if empty
+	Directory string
+	// Labels are stored in Custom1, see SetLabels
+	Labels []string
+	// Priority of the torrent, PriorityNormal if nil
+	Priority *Priority
+	// Throttle is the throttle group of the torrent, see SetThrottle
+	Throttle string
+	// Custom sets d.custom values by key
+	Custom map[string]string
+	// Commands are additional post-load commands, such as "d.peers_max.set=50"
+	Commands []string
+	// Verbose makes rTorrent log loading errors, with load.raw_verbose or
+	// load.verbose
+	Verbose bool
+}
+
+// method returns the load method for `base`, "load.raw" or "load.normal"
+func (o AddOptions) method(base string) string {
+	switch start := o.Start && !o.Paused; {
+	case base == "load.raw" && start && o.Verbose:
+		return "load.raw_start_verbose"
+	case base == "load.raw" && start:
+		return "load.raw_start"
+	case base == "load.raw" && o.Verbose:
+		return "load.raw_verbose"
+	case start && o.Verbose:
+		return "load.start_verbose"
+	case start:
+		return "load.start"
+	case o.Verbose:
+		return "load.verbose"
+	}
+	return base
+}
+
+// commands returns the post-load commands applying the options
+func (o AddOptions) commands() []interface{} {
+	var cmds []interface{}
+	if o.Directory != "" {
+		cmds = append(cmds, "d.directory.set="+quoteArg(o.Directory))
+	}
+	if len(o.Labels) > 0 {
+		cmds = append(cmds, "d.custom1.set="+quoteArg(EncodeLabels(o.Labels...)))
+	}
+	if o.Priority != nil {
+		cmds = append(cmds, fmt.Sprintf("d.priority.set=%d", *o.Priority))
+	}
+	if o.Throttle != "" {
+		cmds = append(cmds, "d.throttle_name.set="+quoteArg(o.Throttle))
+	}
+	keys := make([]string, 0, len(o.Custom))
+	for key := range o.Custom {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		cmds = append(cmds, "d.custom.set="+quoteArg(key)+","+quoteArg(o.Custom[key]))
+	}
+	if o.Paused {
+		cmds = append(cmds, "d.open=")
+	}
+	for _, cmd := range o.Commands {
+		cmds = append(cmds, cmd)
+	}
+	return cmds
+}
+
+// quoteArg quotes a post-load command argument, which may then contain
+// commas and quotes
+func quoteArg(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// InfoHash returns the info-hash of the .torrent `data`, as rTorrent reports
+// it: the upper case hex SHA-1 of the bencoded info dictionary. It fails if
+// `data` isn't valid metainfo, see metainfo.Parse.
+func InfoHash(data []byte) (string, error) {
+	meta, err := metainfo.Parse(data)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid torrent")
+	}
+	return meta.InfoHash(), nil
+}
+
+// MagnetHash returns the info-hash of a magnet link, "" if `uri` isn't one
+func MagnetHash(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "magnet" {
+		return ""
+	}
+	for _, xt := range u.Query()["xt"] {
+		if !strings.HasPrefix(xt, "urn:btih:") {
+			continue
+		}
+		switch hash := strings.TrimPrefix(xt, "urn:btih:"); len(hash) {
+		case 40:
+			if _, err := hex.DecodeString(hash); err == nil {
+				return strings.ToUpper(hash)
+			}
+		case 32:
+			if b, err := base32.StdEncoding.DecodeString(strings.ToUpper(hash)); err == nil {
+				return fmt.Sprintf("%X", b)
+			}
+		}
+	}
+	return ""
+}
+
+// AddTorrentWithOptions adds a new torrent by the torrent files data,
+// configured by `opts`, and returns its info-hash
+func (r *RTorrent) AddTorrentWithOptions(data []byte, opts AddOptions) (string, error) {
+	return r.AddTorrentWithOptionsContext(context.Background(), data, opts)
+}
+
+// AddTorrentWithOptionsContext is like AddTorrentWithOptions but honors ctx
+// cancellation and deadlines
+func (r *RTorrent) AddTorrentWithOptionsContext(ctx context.Context, data []byte, opts AddOptions) (string, error) {
+	meta, err := metainfo.Parse(data)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid torrent")
+	}
+	return r.AddMetaInfoContext(ctx, meta, opts)
+}
+
+// AddMetaInfo adds the torrent `meta`, already parsed with metainfo.Parse,
+// configured by `opts`, and returns its info-hash
+func (r *RTorrent) AddMetaInfo(meta *metainfo.MetaInfo, opts AddOptions) (string, error) {
+	return r.AddMetaInfoContext(context.Background(), meta, opts)
+}
+
+// AddMetaInfoContext is like AddMetaInfo but honors ctx cancellation and
+// deadlines
+func (r *RTorrent) AddMetaInfoContext(ctx context.Context, meta *metainfo.MetaInfo, opts AddOptions) (string, error) {
+	method := opts.method("load.raw")
+	_, err := r.transport.Call(ctx, method, append([]interface{}{"", meta.Raw}, opts.commands()...)...)
+	if err != nil {
+		return "", wrapCallError(method, err)
+	}
+	return meta.InfoHash(), nil
+}
+
+// AddTorrentURLWithOptions adds a new torrent by URL, configured by `opts`.
+// rTorrent fetches the URL in the background: the info-hash is only returned
+// for magnet links, it is empty otherwise.
+func (r *RTorrent) AddTorrentURLWithOptions(url string, opts AddOptions) (string, error) {
+	return r.AddTorrentURLWithOptionsContext(context.Background(), url, opts)
+}
+
+// AddTorrentURLWithOptionsContext is like AddTorrentURLWithOptions but honors
+// ctx cancellation and deadlines
+func (r *RTorrent) AddTorrentURLWithOptionsContext(ctx context.Context, url string, opts AddOptions) (string, error) {
+	method := opts.method("load.normal")
+	_, err := r.transport.Call(ctx, method, append([]interface{}{"", url}, opts.commands()...)...)
+	if err != nil {
+		return "", wrapCallError(method, err)
+	}
+	return MagnetHash(url), nil
+}
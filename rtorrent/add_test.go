@@ -0,0 +1,52 @@
+package rtorrent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMagnetHash(t *testing.T) {
+	for uri, hash := range map[string]string{
+		"magnet:?xt=urn:btih:b7b0fbab74a85d4ac170662c645982a862826455&dn=ubuntu": ubuntuHash,
+		"magnet:?dn=ubuntu&xt=urn:btih:w6ypxk3uvbouvqlqmywgiwmcvbriezcv":         ubuntuHash,
+		"magnet:?xt=urn:btmh:1220caf1e1c30e81cb361b9ee167c4aa64228a7fa4fa9f6105": "",
+		"magnet:?xt=urn:btih:nothex":                                             "",
+		"http://example.com/ubuntu.torrent":                                      "",
+	} {
+		require.Equal(t, hash, MagnetHash(uri), uri)
+	}
+}
+
+func TestAddOptions(t *testing.T) {
+	high := PriorityHigh
+	opts := AddOptions{
+		Start:     true,
+		Directory: `/data/"isos"`,
+		Labels:    []string{"linux", "a,b"},
+		Priority:  &high,
+		Throttle:  "slow",
+		Custom:    map[string]string{"seen": "yes", "addtime": "1"},
+		Commands:  []string{"d.peers_max.set=50"},
+	}
+	require.Equal(t, "load.raw_start", opts.method("load.raw"))
+	require.Equal(t, "load.start", opts.method("load.normal"))
+	require.Equal(t, []interface{}{
+		`d.directory.set="/data/\"isos\""`,
+		`d.custom1.set="linux,a%2Cb"`,
+		`d.priority.set=3`,
+		`d.throttle_name.set="slow"`,
+		`d.custom.set="addtime","1"`,
+		`d.custom.set="seen","yes"`,
+		`d.peers_max.set=50`,
+	}, opts.commands())
+
+	opts = AddOptions{Start: true, Paused: true, Verbose: true}
+	require.Equal(t, "load.raw_verbose", opts.method("load.raw"))
+	require.Equal(t, "load.verbose", opts.method("load.normal"))
+	require.Equal(t, []interface{}{"d.open="}, opts.commands())
+
+	off := PriorityOff
+	opts = AddOptions{Priority: &off}
+	require.Equal(t, []interface{}{"d.priority.set=0"}, opts.commands())
+}
@@ -0,0 +1,153 @@
+package rtorrent
+
+import (
+	"context"
+
+	"github.com/tab1293/go-rtorrent/metainfo"
+)
+
+// Client is the set of operations on an rTorrent instance, implemented by
+// `RTorrent`. Code depending on Client rather than *RTorrent can be unit
+// tested with the in-memory implementation of the rtorrentmock package.
+type Client interface {
+	Shutdown() error
+	ShutdownContext(ctx context.Context) error
+	SetSessionDirectory(d string) error
+	SetSessionDirectoryContext(ctx context.Context, d string) error
+	SetDefaultDirectory(t Torrent, d string) error
+	SetDefaultDirectoryContext(ctx context.Context, t Torrent, d string) error
+
+	GetTorrent(t Torrent) (Torrent, error)
+	GetTorrentContext(ctx context.Context, t Torrent) (Torrent, error)
+	GetTorrentsByHash(hashes ...string) ([]Torrent, error)
+	GetTorrentsByHashContext(ctx context.Context, hashes ...string) ([]Torrent, error)
+	GetStatus(t Torrent) (Status, error)
+	GetStatusContext(ctx context.Context, t Torrent) (Status, error)
+	Torrents(view View) *TorrentQuery
+	GetTorrents(view View) ([]Torrent, error)
+	GetTorrentsContext(ctx context.Context, view View) ([]Torrent, error)
+	AddTorrentURL(url string) error
+	AddTorrentURLContext(ctx context.Context, url string) error
+	AddTorrent(data []byte) error
+	AddTorrentContext(ctx context.Context, data []byte) error
+	AddTorrentURLWithOptions(url string, opts AddOptions) (string, error)
+	AddTorrentURLWithOptionsContext(ctx context.Context, url string, opts AddOptions) (string, error)
+	AddTorrentWithOptions(data []byte, opts AddOptions) (string, error)
+	AddTorrentWithOptionsContext(ctx context.Context, data []byte, opts AddOptions) (string, error)
+	AddMetaInfo(meta *metainfo.MetaInfo, opts AddOptions) (string, error)
+	AddMetaInfoContext(ctx context.Context, meta *metainfo.MetaInfo, opts AddOptions) (string, error)
+	StartTorrent(t Torrent) error
+	StartTorrentContext(ctx context.Context, t Torrent) error
+	StopTorrent(t Torrent) error
+	StopTorrentContext(ctx context.Context, t Torrent) error
+	CloseTorrent(t Torrent) error
+	CloseTorrentContext(ctx context.Context, t Torrent) error
+	Delete(t Torrent) error
+	DeleteContext(ctx context.Context, t Torrent) error
+
+	GetLabel(t Torrent) (string, error)
+	GetLabelContext(ctx context.Context, t Torrent) (string, error)
+	SetLabel(t Torrent, label string) error
+	SetLabelContext(ctx context.Context, t Torrent, label string) error
+	SetLabels(t Torrent, labels ...string) error
+	SetLabelsContext(ctx context.Context, t Torrent, labels ...string) error
+	ListLabels() ([]string, error)
+	ListLabelsContext(ctx context.Context) ([]string, error)
+	GetTorrentsByLabel(view View, label string) ([]Torrent, error)
+	GetTorrentsByLabelContext(ctx context.Context, view View, label string) ([]Torrent, error)
+
+	ListViews() ([]View, error)
+	ListViewsContext(ctx context.Context) ([]View, error)
+	CreateView(name View, filter string) error
+	CreateViewContext(ctx context.Context, name View, filter string) error
+	ClearView(name View) error
+	ClearViewContext(ctx context.Context, name View) error
+	AddToView(t Torrent, name View) error
+	AddToViewContext(ctx context.Context, t Torrent, name View) error
+	RemoveFromView(t Torrent, name View) error
+	RemoveFromViewContext(ctx context.Context, t Torrent, name View) error
+
+	GetTrackers(t Torrent) ([]Tracker, error)
+	GetTrackersContext(ctx context.Context, t Torrent) ([]Tracker, error)
+	AddTracker(t Torrent, url string) error
+	AddTrackerContext(ctx context.Context, t Torrent, url string) error
+	EnableTracker(t Torrent, i int) error
+	EnableTrackerContext(ctx context.Context, t Torrent, i int) error
+	DisableTracker(t Torrent, i int) error
+	DisableTrackerContext(ctx context.Context, t Torrent, i int) error
+	AnnounceNow(t Torrent) error
+	AnnounceNowContext(ctx context.Context, t Torrent) error
+
+	GetPeers(t Torrent) ([]Peer, error)
+	GetPeersContext(ctx context.Context, t Torrent) ([]Peer, error)
+	DisconnectPeer(t Torrent, p Peer) error
+	DisconnectPeerContext(ctx context.Context, t Torrent, p Peer) error
+	BanPeer(t Torrent, p Peer) error
+	BanPeerContext(ctx context.Context, t Torrent, p Peer) error
+	SnubPeer(t Torrent, p Peer) error
+	SnubPeerContext(ctx context.Context, t Torrent, p Peer) error
+	UnsnubPeer(t Torrent, p Peer) error
+	UnsnubPeerContext(ctx context.Context, t Torrent, p Peer) error
+
+	GetFiles(t Torrent) ([]File, error)
+	GetFilesContext(ctx context.Context, t Torrent) ([]File, error)
+	SetFilePrority(t Torrent, i int, p int) error
+	SetFileProrityContext(ctx context.Context, t Torrent, i int, p int) error
+	SetFilePriorities(t Torrent, priorities map[int]int) error
+	SetFilePrioritiesContext(ctx context.Context, t Torrent, priorities map[int]int) error
+	SetAllFilePriorities(t Torrent, p int) error
+	SetAllFilePrioritiesContext(ctx context.Context, t Torrent, p int) error
+
+	DownTotal() (int64, error)
+	DownTotalContext(ctx context.Context) (int64, error)
+	DownRate() (int, error)
+	DownRateContext(ctx context.Context) (int, error)
+	UpTotal() (int64, error)
+	UpTotalContext(ctx context.Context) (int64, error)
+	UpRate() (int, error)
+	UpRateContext(ctx context.Context) (int, error)
+	IP() (string, error)
+	IPContext(ctx context.Context) (string, error)
+	Name() (string, error)
+	NameContext(ctx context.Context) (string, error)
+	DownLimit() (Rate, error)
+	DownLimitContext(ctx context.Context) (Rate, error)
+	SetDownLimit(rate Rate) error
+	SetDownLimitContext(ctx context.Context, rate Rate) error
+	UpLimit() (Rate, error)
+	UpLimitContext(ctx context.Context) (Rate, error)
+	SetUpLimit(rate Rate) error
+	SetUpLimitContext(ctx context.Context, rate Rate) error
+	ThrottleDownLimit(name string) (Rate, error)
+	ThrottleDownLimitContext(ctx context.Context, name string) (Rate, error)
+	SetThrottleDownLimit(name string, rate Rate) error
+	SetThrottleDownLimitContext(ctx context.Context, name string, rate Rate) error
+	ThrottleUpLimit(name string) (Rate, error)
+	ThrottleUpLimitContext(ctx context.Context, name string) (Rate, error)
+	SetThrottleUpLimit(name string, rate Rate) error
+	SetThrottleUpLimitContext(ctx context.Context, name string, rate Rate) error
+	SetThrottle(t Torrent, name string) error
+	SetThrottleContext(ctx context.Context, t Torrent, name string) error
+	MaxPeers() (int, error)
+	MaxPeersContext(ctx context.Context) (int, error)
+	SetMaxPeers(n int) error
+	SetMaxPeersContext(ctx context.Context, n int) error
+	MaxPeersSeed() (int, error)
+	MaxPeersSeedContext(ctx context.Context) (int, error)
+	SetMaxPeersSeed(n int) error
+	SetMaxPeersSeedContext(ctx context.Context, n int) error
+	MaxUploads() (int, error)
+	MaxUploadsContext(ctx context.Context) (int, error)
+	SetMaxUploads(n int) error
+	SetMaxUploadsContext(ctx context.Context, n int) error
+	MaxUploadsGlobal() (int, error)
+	MaxUploadsGlobalContext(ctx context.Context) (int, error)
+	SetMaxUploadsGlobal(n int) error
+	SetMaxUploadsGlobalContext(ctx context.Context, n int) error
+	ListMethods() ([]string, error)
+	ListMethodsContext(ctx context.Context) ([]string, error)
+	MethodSignature(methodName string) (string, error)
+	MethodSignatureContext(ctx context.Context, methodName string) (string, error)
+}
+
+var _ Client = (*RTorrent)(nil)
@@ -0,0 +1,658 @@
+package rtorrent
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tab1293/go-rtorrent/bencode"
+	"github.com/tab1293/go-rtorrent/jsonrpc"
+	"github.com/tab1293/go-rtorrent/metainfo"
+	"github.com/tab1293/go-rtorrent/rtorrent/rtorrenttest"
+	"github.com/tab1293/go-rtorrent/xmlrpc"
+)
+
+const (
+	ubuntuHash = "B7B0FBAB74A85D4AC170662C645982A862826455"
+	ubuntuName = "ubuntu-19.04-live-server-amd64.iso"
+	ubuntuSize = int64(784334848)
+)
+
+func ubuntuTorrent(t *testing.T) []byte {
+	data, err := ioutil.ReadFile("testdata/ubuntu-19.04-live-server-amd64.iso.torrent")
+	require.NoError(t, err)
+	return data
+}
+
+// forEachServer runs `test` against a new, empty fake server for each
+// transport, over XML-RPC and JSON-RPC. `prefix` is the scheme prefix of the
+// protocol, jsonrpc.SchemePrefix or empty.
+func forEachServer(t *testing.T, test func(t *testing.T, srv *rtorrenttest.Server, client *RTorrent, prefix string)) {
+	servers := map[string]func() *rtorrenttest.Server{
+		"http": rtorrenttest.NewServer,
+		"scgi": rtorrenttest.NewSCGIServer,
+	}
+	for name, newServer := range servers {
+		for _, prefix := range []string{"", jsonrpc.SchemePrefix} {
+			newServer, prefix := newServer, prefix
+			t.Run(prefix+name, func(t *testing.T) {
+				srv := newServer()
+				defer srv.Close()
+				test(t, srv, New(prefix+srv.URL, false), prefix)
+			})
+		}
+	}
+}
+
+// addUbuntu loads the ubuntu torrent into `srv`, stopped
+func addUbuntu(t *testing.T, srv *rtorrenttest.Server) Torrent {
+	hash, err := srv.AddTorrent(ubuntuTorrent(t))
+	require.NoError(t, err)
+	return Torrent{Hash: hash}
+}
+
+// seedUbuntu loads the ubuntu torrent into `srv`, started and complete, with
+// a ratio of 1.5
+func seedUbuntu(t *testing.T, srv *rtorrenttest.Server) Torrent {
+	torrent := addUbuntu(t, srv)
+	require.True(t, srv.Update(torrent.Hash, func(t *rtorrenttest.Torrent) {
+		t.Open, t.Started = true, true
+		t.StartedAt = time.Now()
+		t.CompletedBytes = t.Size
+		t.UpTotal = t.Size * 3 / 2
+	}))
+	return torrent
+}
+
+func TestInstance(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *rtorrenttest.Server, client *RTorrent, prefix string) {
+		ip, err := client.IP()
+		require.NoError(t, err)
+		require.Equal(t, "0.0.0.0", ip)
+
+		name, err := client.Name()
+		require.NoError(t, err)
+		require.Equal(t, "rtorrenttest", name)
+
+		srv.UpdateGlobal(func(g *rtorrenttest.Global) { g.DownTotal = 1 << 40 })
+		total, err := client.DownTotal()
+		require.NoError(t, err)
+		require.Equal(t, int64(1<<40), total)
+
+		methods, err := client.ListMethods()
+		require.NoError(t, err)
+		require.Contains(t, methods, "d.multicall2")
+	})
+}
+
+func TestAddTorrent(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *rtorrenttest.Server, client *RTorrent, prefix string) {
+		data := ubuntuTorrent(t)
+
+		calls := len(srv.Calls())
+		require.Error(t, client.AddTorrent(data[:len(data)-1]), "truncated torrent")
+		require.Len(t, srv.Calls(), calls, "invalid torrents aren't sent")
+
+		require.NoError(t, client.AddTorrent(data))
+
+		torrents, err := client.GetTorrents(ViewMain)
+		require.NoError(t, err)
+		require.Len(t, torrents, 1)
+		require.Equal(t, ubuntuHash, torrents[0].Hash)
+		require.Equal(t, ubuntuName, torrents[0].Name)
+		require.Equal(t, "/downloads/"+ubuntuName, torrents[0].Path)
+		require.Equal(t, ubuntuSize, torrents[0].Size)
+		require.False(t, torrents[0].Completed)
+
+		torrents, err = client.GetTorrents(ViewStarted)
+		require.NoError(t, err)
+		require.Empty(t, torrents)
+
+		require.Error(t, client.AddTorrent(data), "duplicate torrent")
+
+		// rTorrent loads torrents whose optional keys have another type
+		require.NoError(t, client.Delete(Torrent{Hash: ubuntuHash}))
+		var meta map[string]bencode.RawMessage
+		require.NoError(t, bencode.Unmarshal(data, &meta))
+		meta["creation date"] = bencode.RawMessage("10:2019-04-18")
+		odd, err := bencode.Marshal(meta)
+		require.NoError(t, err)
+		require.NoError(t, client.AddTorrent(odd))
+		torrent, err := client.GetTorrent(Torrent{Hash: ubuntuHash})
+		require.NoError(t, err)
+		require.Equal(t, ubuntuName, torrent.Name)
+	})
+}
+
+func TestProgress(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *rtorrenttest.Server, client *RTorrent, prefix string) {
+		addUbuntu(t, srv)
+
+		require.NoError(t, client.StartTorrent(Torrent{Hash: ubuntuHash}))
+		require.True(t, srv.Update(ubuntuHash, func(t *rtorrenttest.Torrent) {
+			t.CompletedBytes = t.Size
+			t.UpTotal = t.Size * 3 / 2
+		}))
+
+		torrent, err := client.GetTorrent(Torrent{Hash: ubuntuHash})
+		require.NoError(t, err)
+		require.True(t, torrent.Completed)
+		require.Equal(t, 1.5, torrent.Ratio)
+		require.Equal(t, int64(0), torrent.LeftBytes)
+		require.Equal(t, ubuntuSize*3/2, torrent.UpTotal)
+		require.Equal(t, PriorityNormal, torrent.Priority)
+		require.Equal(t, "/downloads", torrent.Directory)
+		require.True(t, torrent.IsOpen)
+		require.True(t, torrent.IsActive)
+		require.False(t, torrent.IsPrivate)
+		require.Equal(t, torrent.SizeChunks, torrent.CompletedChunks)
+		require.Equal(t, int64(1555564384), torrent.CreationDate.Unix())
+		require.False(t, torrent.LoadDate.IsZero())
+		require.False(t, torrent.StartedAt.IsZero())
+		require.True(t, torrent.FinishedAt.IsZero())
+
+		torrents, err := client.GetTorrents(ViewSeeding)
+		require.NoError(t, err)
+		require.Len(t, torrents, 1)
+	})
+}
+
+func TestGetStatus(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *rtorrenttest.Server, client *RTorrent, prefix string) {
+		seedUbuntu(t, srv)
+
+		status, err := client.GetStatus(Torrent{Hash: ubuntuHash})
+		require.NoError(t, err)
+		require.Equal(t, StatusSeeding, status.Code)
+		require.Equal(t, 100.0, status.Progress)
+		require.Equal(t, time.Duration(0), status.ETA)
+
+		require.True(t, srv.Update(ubuntuHash, func(t *rtorrenttest.Torrent) {
+			t.CompletedBytes = t.Size / 4
+			t.DownRate = 1 << 20
+			t.Message = "Tracker: [Failure reason \"torrent not registered\"]"
+		}))
+		status, err = client.GetStatus(Torrent{Hash: ubuntuHash})
+		require.NoError(t, err)
+		require.Equal(t, StatusError, status.Code)
+		require.Equal(t, 25.0, status.Progress)
+		require.Equal(t, time.Duration(ubuntuSize*3/4/(1<<20))*time.Second, status.ETA)
+
+		_, err = client.GetStatus(Torrent{Hash: "0000000000000000000000000000000000000000"})
+		require.Equal(t, ErrTorrentNotFound, err)
+	})
+}
+
+func TestLookupByHash(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *rtorrenttest.Server, client *RTorrent, prefix string) {
+		seedUbuntu(t, srv)
+
+		before := len(srv.Calls())
+		torrent, err := client.GetTorrent(Torrent{Hash: ubuntuHash})
+		require.NoError(t, err)
+		require.Equal(t, ubuntuName, torrent.Name)
+		require.NotContains(t, srv.Calls()[before:], "d.multicall2")
+
+		unknown := "0000000000000000000000000000000000000000"
+		_, err = client.GetTorrent(Torrent{Hash: unknown})
+		require.Equal(t, ErrTorrentNotFound, err)
+
+		torrents, err := client.GetTorrentsByHash(unknown, ubuntuHash)
+		require.NoError(t, err)
+		require.Len(t, torrents, 1)
+		require.Equal(t, ubuntuHash, torrents[0].Hash)
+		require.Equal(t, 1.5, torrents[0].Ratio)
+
+		torrents, err = client.GetTorrentsByHash()
+		require.NoError(t, err)
+		require.Empty(t, torrents)
+	})
+}
+
+// missingMethodTransport fails the calls to `method` within multicalls, as
+// an rTorrent without that command would
+type missingMethodTransport struct {
+	Transport
+	method string
+}
+
+func (t missingMethodTransport) Multicall(ctx context.Context, calls []Call) ([]CallResult, error) {
+	results, err := t.Transport.Multicall(ctx, calls)
+	for i, call := range calls {
+		if err == nil && call.Method == t.method {
+			results[i] = CallResult{Err: &xmlrpc.Fault{Code: xmlrpc.FaultMethodNotFound, Message: "Method '" + t.method + "' not defined"}}
+		}
+	}
+	return results, err
+}
+
+func TestLookupWithMissingMethod(t *testing.T) {
+	srv := rtorrenttest.NewServer()
+	defer srv.Close()
+	seedUbuntu(t, srv)
+	transport := NewXMLRPCTransport(xmlrpc.NewClient(srv.URL, false))
+	client := NewWithTransport(missingMethodTransport{Transport: transport, method: "d.load_date"})
+
+	torrent, err := client.GetTorrent(Torrent{Hash: ubuntuHash})
+	require.NoError(t, err)
+	require.Equal(t, ubuntuName, torrent.Name)
+	require.True(t, torrent.LoadDate.IsZero())
+
+	torrents, err := client.GetTorrentsByHash("0000000000000000000000000000000000000000", ubuntuHash)
+	require.NoError(t, err)
+	require.Len(t, torrents, 1)
+	require.Equal(t, 1.5, torrents[0].Ratio)
+}
+
+func TestTorrentQuery(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *rtorrenttest.Server, client *RTorrent, prefix string) {
+		seedUbuntu(t, srv)
+
+		srv.Update(ubuntuHash, func(t *rtorrenttest.Torrent) {
+			t.DownRate = 1024
+			t.Custom["1"] = "linux"
+			t.Custom["seen"] = "yes"
+		})
+		for _, hashes := range [][]string{nil, {ubuntuHash}} {
+			q := client.Torrents(ViewMain).
+				Fields(FieldHash, FieldDownRate, FieldRatio).
+				Fields(FieldCustom(1), FieldCustomKey("seen"))
+			if hashes != nil {
+				q = q.Hashes(hashes...)
+			}
+			torrents, err := q.Do()
+			require.NoError(t, err)
+			require.Equal(t, []Torrent{{
+				Hash:     ubuntuHash,
+				DownRate: 1024,
+				Ratio:    1.5,
+				Custom1:  "linux",
+				Label:    "linux",
+				Extra:    map[string]interface{}{"d.custom=seen": "yes"},
+			}}, torrents)
+		}
+
+		torrents, err := client.Torrents(ViewSeeding).Do()
+		require.NoError(t, err)
+		require.Len(t, torrents, 1)
+		require.Equal(t, ubuntuName, torrents[0].Name)
+		require.NotZero(t, torrents[0].ChunkSize)
+		require.Nil(t, torrents[0].Extra)
+	})
+}
+
+func TestLabels(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *rtorrenttest.Server, client *RTorrent, prefix string) {
+		addUbuntu(t, srv)
+
+		torrent := Torrent{Hash: ubuntuHash}
+		require.NoError(t, client.SetLabel(torrent, "TestLabel"))
+		label, err := client.GetLabel(torrent)
+		require.NoError(t, err)
+		require.Equal(t, "TestLabel", label)
+
+		require.NoError(t, client.SetLabels(torrent, "Linux ISOs", "a,b"))
+		stored, _ := srv.Torrent(ubuntuHash)
+		require.Equal(t, "Linux%20ISOs,a%2Cb", stored.Custom["1"])
+		torrents, err := client.GetTorrents(ViewMain)
+		require.NoError(t, err)
+		require.Equal(t, []string{"Linux ISOs", "a,b"}, torrents[0].Labels())
+		require.Equal(t, "Linux ISOs", torrents[0].Label)
+		label, err = client.GetLabel(torrent)
+		require.NoError(t, err)
+		require.Equal(t, "Linux ISOs", label)
+
+		labels, err := client.ListLabels()
+		require.NoError(t, err)
+		require.Equal(t, []string{"Linux ISOs", "a,b"}, labels)
+
+		torrents, err = client.GetTorrentsByLabel(ViewMain, "a,b")
+		require.NoError(t, err)
+		require.Len(t, torrents, 1)
+		torrents, err = client.GetTorrentsByLabel(ViewMain, "")
+		require.NoError(t, err)
+		require.Empty(t, torrents)
+		torrents, err = client.Torrents(ViewMain).Fields(FieldHash).Label("Linux ISOs").Do()
+		require.NoError(t, err)
+		require.Equal(t, []Torrent{{Hash: ubuntuHash, Custom1: "Linux%20ISOs,a%2Cb", Label: "Linux ISOs"}}, torrents)
+
+		require.NoError(t, client.SetLabel(torrent, ""))
+		torrents, err = client.GetTorrentsByLabel(ViewMain, "")
+		require.NoError(t, err)
+		require.Len(t, torrents, 1)
+		require.Equal(t, "", torrents[0].Label)
+
+		_, err = client.GetLabel(Torrent{Hash: "0000000000000000000000000000000000000000"})
+		require.True(t, errors.Is(err, ErrTorrentNotFound))
+	})
+}
+
+func TestFiles(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *rtorrenttest.Server, client *RTorrent, prefix string) {
+		addUbuntu(t, srv)
+
+		files, err := client.GetFiles(Torrent{Hash: ubuntuHash})
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+		require.Equal(t, ubuntuName, files[0].Path)
+		require.Equal(t, ubuntuSize, files[0].Size)
+		require.Equal(t, 1, files[0].Priority)
+
+		require.NoError(t, client.SetAllFilePriorities(Torrent{Hash: ubuntuHash}, 0))
+		files, err = client.GetFiles(Torrent{Hash: ubuntuHash})
+		require.NoError(t, err)
+		require.Equal(t, 0, files[0].Priority)
+	})
+}
+
+func TestPeers(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *rtorrenttest.Server, client *RTorrent, prefix string) {
+		addUbuntu(t, srv)
+
+		torrent := Torrent{Hash: ubuntuHash}
+		require.True(t, srv.Update(ubuntuHash, func(t *rtorrenttest.Torrent) {
+			t.Peers = []rtorrenttest.Peer{
+				{ID: "2D5554333535532D", Address: "10.0.0.1", Port: 51413, ClientVersion: "uTorrent 3.5.5", CompletedPercent: 40, DownRate: 2048, DownTotal: 1 << 20, Encrypted: true},
+				{ID: "2D4C54313030302D", Address: "10.0.0.2", Port: 6881, ClientVersion: "libTorrent 0.13.8", Incoming: true},
+				{ID: "2D7142343235302D", Address: "10.0.0.3", Port: 6882, ClientVersion: "qBittorrent 4.2.5"},
+			}
+		}))
+		peers, err := client.GetPeers(torrent)
+		require.NoError(t, err)
+		require.Len(t, peers, 3)
+		require.Equal(t, Peer{
+			ID:               "2D5554333535532D",
+			Address:          "10.0.0.1",
+			Port:             51413,
+			ClientVersion:    "uTorrent 3.5.5",
+			CompletedPercent: 40,
+			DownRate:         2048,
+			DownTotal:        1 << 20,
+			IsEncrypted:      true,
+		}, peers[0])
+		require.True(t, peers[1].IsIncoming)
+
+		require.NoError(t, client.SnubPeer(torrent, peers[0]))
+		require.NoError(t, client.BanPeer(torrent, peers[1]))
+		require.NoError(t, client.DisconnectPeer(torrent, peers[2]))
+		stored, _ := srv.Torrent(ubuntuHash)
+		require.Len(t, stored.Peers, 1)
+		require.True(t, stored.Peers[0].Snubbed)
+		peers, err = client.GetPeers(torrent)
+		require.NoError(t, err)
+		require.Len(t, peers, 1)
+		require.True(t, peers[0].IsSnubbed)
+		require.NoError(t, client.UnsnubPeer(torrent, peers[0]))
+
+		err = client.BanPeer(torrent, Peer{ID: "2D4C54313030302D"})
+		require.True(t, errors.Is(err, ErrInvalidArgument))
+	})
+}
+
+func TestThrottles(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *rtorrenttest.Server, client *RTorrent, prefix string) {
+		seedUbuntu(t, srv)
+
+		require.NoError(t, client.SetDownLimit(2*MiBPerSecond))
+		require.NoError(t, client.SetUpLimit(512*KiBPerSecond))
+		down, err := client.DownLimit()
+		require.NoError(t, err)
+		require.Equal(t, 2*MiBPerSecond, down)
+		up, err := client.UpLimit()
+		require.NoError(t, err)
+		require.Equal(t, 512*KiBPerSecond, up)
+
+		require.NoError(t, client.SetThrottleDownLimit("slow", 100*KiBPerSecond+10))
+		require.NoError(t, client.SetThrottleUpLimit("slow", 50*KiBPerSecond))
+		down, err = client.ThrottleDownLimit("slow")
+		require.NoError(t, err)
+		require.Equal(t, 100*KiBPerSecond, down)
+		up, err = client.ThrottleUpLimit("slow")
+		require.NoError(t, err)
+		require.Equal(t, 50*KiBPerSecond, up)
+		_, err = client.ThrottleUpLimit("unknown")
+		require.True(t, errors.Is(err, ErrInvalidArgument))
+
+		require.NoError(t, client.SetThrottleDownLimit("tiny", 100*BytesPerSecond))
+		down, err = client.ThrottleDownLimit("tiny")
+		require.NoError(t, err)
+		require.Equal(t, KiBPerSecond, down, "small rates aren't rounded down to unlimited")
+		up, err = client.ThrottleUpLimit("tiny")
+		require.NoError(t, err)
+		require.Equal(t, Rate(0), up, "unthrottled")
+
+		torrent := Torrent{Hash: ubuntuHash}
+		require.True(t, errors.Is(client.SetThrottle(torrent, "slow"), ErrInvalidArgument))
+		require.NoError(t, client.StopTorrent(torrent))
+		require.NoError(t, client.SetThrottle(torrent, "slow"))
+		require.NoError(t, client.StartTorrent(torrent))
+		got, err := client.GetTorrent(torrent)
+		require.NoError(t, err)
+		require.Equal(t, "slow", got.ThrottleName)
+
+		require.NoError(t, client.SetMaxPeers(60))
+		require.NoError(t, client.SetMaxPeersSeed(30))
+		require.NoError(t, client.SetMaxUploads(8))
+		require.NoError(t, client.SetMaxUploadsGlobal(200))
+		for _, get := range []struct {
+			fn   func() (int, error)
+			want int
+		}{{client.MaxPeers, 60}, {client.MaxPeersSeed, 30}, {client.MaxUploads, 8}, {client.MaxUploadsGlobal, 200}} {
+			n, err := get.fn()
+			require.NoError(t, err)
+			require.Equal(t, get.want, n)
+		}
+		global := srv.Global()
+		require.Equal(t, int64(2<<20), global.DownLimit)
+		require.Equal(t, rtorrenttest.Throttle{DownLimit: 100 << 10, UpLimit: 50 << 10}, global.Throttles["slow"])
+	})
+}
+
+func TestViews(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *rtorrenttest.Server, client *RTorrent, prefix string) {
+		addUbuntu(t, srv)
+
+		torrent := Torrent{Hash: ubuntuHash}
+		require.NoError(t, client.CreateView("team-a", ""))
+		require.NoError(t, client.CreateView("big", "d.is_multi_file="))
+		views, err := client.ListViews()
+		require.NoError(t, err)
+		require.Contains(t, views, ViewSeeding)
+		require.Equal(t, []View{"team-a", "big"}, views[len(views)-2:])
+		err = client.CreateView("team-a", "")
+		require.True(t, errors.Is(err, ErrInvalidArgument))
+
+		torrents, err := client.GetTorrents("team-a")
+		require.NoError(t, err)
+		require.Empty(t, torrents)
+		require.NoError(t, client.AddToView(torrent, "team-a"))
+		torrents, err = client.GetTorrents("team-a")
+		require.NoError(t, err)
+		require.Len(t, torrents, 1)
+		require.Equal(t, ubuntuName, torrents[0].Name)
+		torrents, err = client.GetTorrents("big")
+		require.NoError(t, err)
+		require.Empty(t, torrents)
+
+		require.NoError(t, client.RemoveFromView(torrent, "team-a"))
+		torrents, err = client.GetTorrents("team-a")
+		require.NoError(t, err)
+		require.Empty(t, torrents)
+
+		require.NoError(t, client.ClearView("big"))
+		require.NoError(t, client.AddToView(torrent, "team-a"))
+		require.NoError(t, client.ClearView("team-a"))
+		torrents, err = client.GetTorrents("team-a")
+		require.NoError(t, err)
+		require.Empty(t, torrents)
+		views, err = client.ListViews()
+		require.NoError(t, err)
+		require.Contains(t, views, View("team-a"), "cleared views are still listed")
+		err = client.CreateView("team-a", "")
+		require.True(t, errors.Is(err, ErrInvalidArgument), "cleared views can't be created again")
+
+		_, err = client.GetTorrents("unknown")
+		require.True(t, errors.Is(err, ErrInvalidArgument))
+		err = client.AddToView(torrent, "unknown")
+		require.True(t, errors.Is(err, ErrInvalidArgument))
+	})
+}
+
+func TestTrackers(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *rtorrenttest.Server, client *RTorrent, prefix string) {
+		addUbuntu(t, srv)
+
+		torrent := Torrent{Hash: ubuntuHash}
+		trackers, err := client.GetTrackers(torrent)
+		require.NoError(t, err)
+		require.Equal(t, []Tracker{
+			{URL: "http://torrent.ubuntu.com:6969/announce", Type: TrackerHTTP, Enabled: true},
+			{URL: "http://ipv6.torrent.ubuntu.com:6969/announce", Group: 1, Type: TrackerHTTP, Enabled: true, Index: 1},
+		}, trackers)
+
+		require.NoError(t, client.AddTracker(torrent, "udp://tracker.example.com:1337/announce"))
+		require.NoError(t, client.DisableTracker(torrent, 1))
+		require.NoError(t, client.AnnounceNow(torrent))
+		trackers, err = client.GetTrackers(torrent)
+		require.NoError(t, err)
+		require.Len(t, trackers, 3)
+		require.False(t, trackers[1].Enabled)
+		require.True(t, trackers[1].LastAnnounce.IsZero())
+		require.Equal(t, TrackerUDP, trackers[2].Type)
+		require.Equal(t, 1, trackers[2].SuccessCounter)
+		require.False(t, trackers[2].LastAnnounce.IsZero())
+
+		require.NoError(t, client.EnableTracker(torrent, 1))
+		trackers, err = client.GetTrackers(torrent)
+		require.NoError(t, err)
+		require.True(t, trackers[1].Enabled)
+
+		err = client.DisableTracker(torrent, 5)
+		require.True(t, errors.Is(err, ErrInvalidArgument))
+		_, err = client.GetTrackers(Torrent{Hash: "0000000000000000000000000000000000000000"})
+		require.True(t, errors.Is(err, ErrTorrentNotFound))
+	})
+}
+
+func TestDelete(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *rtorrenttest.Server, client *RTorrent, prefix string) {
+		addUbuntu(t, srv)
+
+		require.NoError(t, client.Delete(Torrent{Hash: ubuntuHash}))
+		torrents, err := client.GetTorrents(ViewMain)
+		require.NoError(t, err)
+		require.Empty(t, torrents)
+
+		err = client.StartTorrent(Torrent{Hash: ubuntuHash})
+		require.True(t, errors.Is(err, ErrTorrentNotFound), "got %v", err)
+		_, err = client.GetTorrent(Torrent{Hash: ubuntuHash})
+		require.Equal(t, ErrTorrentNotFound, err)
+	})
+}
+
+func TestAddTorrentURL(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *rtorrenttest.Server, client *RTorrent, prefix string) {
+		data := ubuntuTorrent(t)
+
+		srv.RegisterURL("http://example.com/ubuntu.torrent", data)
+		require.NoError(t, client.AddTorrentURL("http://example.com/ubuntu.torrent"))
+		_, ok := srv.Torrent(ubuntuHash)
+		require.True(t, ok)
+
+		err := client.AddTorrentURL("http://example.com/unregistered.torrent")
+		require.True(t, errors.Is(err, ErrInvalidArgument), "got %v", err)
+	})
+}
+
+func TestFaults(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *rtorrenttest.Server, client *RTorrent, prefix string) {
+		addUbuntu(t, srv)
+
+		err := client.SetFilePrority(Torrent{Hash: "0000000000000000000000000000000000000000"}, 0, 1)
+		require.True(t, errors.Is(err, ErrTorrentNotFound), "got %v", err)
+		var callErr *CallError
+		require.True(t, errors.As(err, &callErr))
+		require.Equal(t, "f.priority.set", callErr.Method)
+		require.True(t, strings.HasPrefix(err.Error(), "f.priority.set call failed: "), err.Error())
+
+		_, err = client.MethodSignature("d.bogus")
+		require.True(t, errors.Is(err, ErrMethodNotFound), "got %v", err)
+
+		err = client.SetFilePrority(Torrent{Hash: ubuntuHash}, 7, 1)
+		require.True(t, errors.Is(err, ErrInvalidArgument), "got %v", err)
+		require.False(t, errors.Is(err, ErrTorrentNotFound))
+		if prefix == "" {
+			var fault *xmlrpc.Fault
+			require.True(t, errors.As(err, &fault))
+			require.Equal(t, xmlrpc.FaultInvalidArgument, fault.Code)
+		} else {
+			var jsonErr *jsonrpc.Error
+			require.True(t, errors.As(err, &jsonErr))
+			require.Equal(t, xmlrpc.FaultInvalidArgument, jsonErr.Code)
+		}
+	})
+}
+
+func TestAddTorrentWithOptions(t *testing.T) {
+	forEachServer(t, func(t *testing.T, srv *rtorrenttest.Server, client *RTorrent, prefix string) {
+		data := ubuntuTorrent(t)
+		require.NoError(t, client.SetThrottleDownLimit("slow", 100*KiBPerSecond))
+
+		high, off := PriorityHigh, PriorityOff
+		hash, err := client.AddTorrentWithOptions(data, AddOptions{
+			Start:     true,
+			Directory: "/data/isos",
+			Labels:    []string{"linux", "a,b"},
+			Priority:  &high,
+			Throttle:  "slow",
+			Custom:    map[string]string{"seen": "yes"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, ubuntuHash, hash)
+		stored, ok := srv.Torrent(ubuntuHash)
+		require.True(t, ok)
+		require.Equal(t, "/data/isos", stored.Directory)
+		require.Equal(t, 3, stored.Priority)
+		require.Equal(t, "slow", stored.ThrottleName)
+		require.Equal(t, "yes", stored.Custom["seen"])
+		require.True(t, stored.Started)
+		torrent, err := client.GetTorrent(Torrent{Hash: ubuntuHash})
+		require.NoError(t, err)
+		require.Equal(t, []string{"linux", "a,b"}, torrent.Labels())
+
+		require.NoError(t, client.Delete(Torrent{Hash: ubuntuHash}))
+		_, err = client.AddTorrentWithOptions(data, AddOptions{Commands: []string{"d.bogus="}})
+		require.True(t, errors.Is(err, ErrMethodNotFound), "got %v", err)
+		_, ok = srv.Torrent(ubuntuHash)
+		require.False(t, ok, "failed post-load commands remove the torrent")
+
+		_, err = client.AddTorrentWithOptions([]byte("not bencode"), AddOptions{})
+		require.Error(t, err)
+
+		srv.RegisterURL("http://example.com/ubuntu.torrent", data)
+		hash, err = client.AddTorrentURLWithOptions("http://example.com/ubuntu.torrent", AddOptions{Paused: true, Priority: &off})
+		require.NoError(t, err)
+		require.Empty(t, hash)
+		status, err := client.GetStatus(Torrent{Hash: ubuntuHash})
+		require.NoError(t, err)
+		require.Equal(t, StatusStopped, status.Code)
+		stored, _ = srv.Torrent(ubuntuHash)
+		require.True(t, stored.Open)
+		require.Equal(t, 0, stored.Priority)
+
+		require.NoError(t, client.Delete(Torrent{Hash: ubuntuHash}))
+		meta, err := metainfo.Parse(data)
+		require.NoError(t, err)
+		hash, err = client.AddMetaInfo(meta, AddOptions{Start: true, Throttle: "slow"})
+		require.NoError(t, err)
+		require.Equal(t, ubuntuHash, hash)
+		stored, ok = srv.Torrent(ubuntuHash)
+		require.True(t, ok)
+		require.True(t, stored.Started)
+		require.Equal(t, "slow", stored.ThrottleName)
+	})
+}
@@ -0,0 +1,135 @@
+package rtorrent
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/tab1293/go-rtorrent/jsonrpc"
+	"github.com/tab1293/go-rtorrent/xmlrpc"
+)
+
+var (
+	// ErrTorrentNotFound is matched by errors for calls targeting an
+	// info-hash rTorrent doesn't know
+	ErrTorrentNotFound = errors.New("torrent not found")
+	// ErrMethodNotFound is matched by errors for calls to commands this
+	// rTorrent doesn't have, e.g. because it is too old
+	ErrMethodNotFound = errors.New("method not found")
+	// ErrInvalidArgument is matched by errors for calls rTorrent rejected
+	// because of their target or params
+	ErrInvalidArgument = errors.New("invalid argument")
+)
+
+// UnexpectedResponseError is returned when rTorrent answers a call with a
+// value of an unexpected shape or type, e.g. a string where a size was expected
+type UnexpectedResponseError struct {
+	// Method is the XML-RPC method that was called
+	Method string
+	// Field is the command or member the value was returned for,
+	// empty if the whole result was unexpected
+	Field string
+	// Value is the offending value
+	Value interface{}
+	// Expected describes what was expected instead
+	Expected string
+}
+
+func (e *UnexpectedResponseError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("unexpected %s response: got %T %#v, expected %s", e.Method, e.Value, e.Value, e.Expected)
+	}
+	return fmt.Sprintf("unexpected %s response for %s: got %T %#v, expected %s", e.Method, e.Field, e.Value, e.Value, e.Expected)
+}
+
+// singleResult unwraps the single value of a methodResponse params list
+func singleResult(method string, result interface{}) (interface{}, error) {
+	params, ok := result.([]interface{})
+	if !ok || len(params) != 1 {
+		return nil, &UnexpectedResponseError{Method: method, Value: result, Expected: "a single value"}
+	}
+	return params[0], nil
+}
+
+// CallError is returned when rTorrent failed a call. It unwraps to the
+// error returned by the Transport, an *xmlrpc.Fault, *jsonrpc.Error or
+// *xmlrpc.TransportError, and matches the sentinel error of known faults:
+//
+//	if errors.Is(err, rtorrent.ErrTorrentNotFound) { ... }
+//	var fault *xmlrpc.Fault
+//	if errors.As(err, &fault) { ... fault.Code ... }
+type CallError struct {
+	// Method is the command that failed
+	Method string
+	// Err is the error returned by the Transport
+	Err error
+
+	sentinel error
+}
+
+func (e *CallError) Error() string {
+	return fmt.Sprintf("%s call failed: %v", e.Method, e.Err)
+}
+
+// Unwrap returns the error returned by the Transport
+func (e *CallError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the sentinel error of this fault
+func (e *CallError) Is(target error) bool {
+	return e.sentinel != nil && target == e.sentinel
+}
+
+// wrapCallError adds the failed `method` to a Transport error, leaving an
+// UnexpectedResponseError as is
+func wrapCallError(method string, err error) error {
+	if _, ok := err.(*UnexpectedResponseError); ok {
+		return err
+	}
+	return &CallError{Method: method, Err: err, sentinel: faultSentinel(err)}
+}
+
+// faultSentinel maps the fault within err to its sentinel error, nil if err
+// isn't a known fault. rTorrent reports an unknown info-hash with
+// "Could not find info-hash." and other bad targets and params as -501
+// (input_error), unknown commands as -506 "Method 'x' not defined".
+func faultSentinel(err error) error {
+	var code int
+	var message string
+	var fault *xmlrpc.Fault
+	var jsonErr *jsonrpc.Error
+	switch {
+	case errors.As(err, &fault):
+		code, message = fault.Code, fault.Message
+	case errors.As(err, &jsonErr):
+		code, message = jsonErr.Code, jsonErr.Message
+	default:
+		return nil
+	}
+
+	switch {
+	case message == "Could not find info-hash.":
+		return ErrTorrentNotFound
+	case code == xmlrpc.FaultMethodNotFound, code == jsonrpc.CodeMethodNotFound:
+		return ErrMethodNotFound
+	case code == xmlrpc.FaultInvalidArgument, code == jsonrpc.CodeInvalidParams:
+		return ErrInvalidArgument
+	}
+	return nil
+}
+
+// decodeError turns an xmlrpc.DecodeError into an UnexpectedResponseError for `method`
+func decodeError(method string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if de, ok := err.(*xmlrpc.DecodeError); ok {
+		return &UnexpectedResponseError{
+			Method:   method,
+			Field:    de.Field,
+			Value:    de.Value,
+			Expected: de.Type.String(),
+		}
+	}
+	return errors.Wrapf(err, "failed to decode %s response", method)
+}
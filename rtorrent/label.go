@@ -0,0 +1,143 @@
+package rtorrent
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Labels are stored in d.custom1, as ruTorrent does: URL-encoded, several
+// labels being separated by commas. A comma within a label is encoded.
+
+// EncodeLabels encodes labels into the d.custom1 format, for instance to
+// script the Custom1 member of torrents in tests
+func EncodeLabels(labels ...string) string {
+	encoded := make([]string, 0, len(labels))
+	for _, label := range labels {
+		if label != "" {
+			encoded = append(encoded, url.PathEscape(label))
+		}
+	}
+	return strings.Join(encoded, ",")
+}
+
+// decodeLabel decodes a d.custom1 value, which may not be URL-encoded if set
+// by another client
+func decodeLabel(custom1 string) string {
+	label, err := url.PathUnescape(custom1)
+	if err != nil {
+		return custom1
+	}
+	return label
+}
+
+// Labels returns the labels stored in Custom1, which must have been fetched
+func (t *Torrent) Labels() []string {
+	var labels []string
+	for _, label := range strings.Split(t.Custom1, ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			labels = append(labels, decodeLabel(label))
+		}
+	}
+	return labels
+}
+
+// firstLabel returns the first label stored in a d.custom1 value, empty if
+// there are none
+func firstLabel(custom1 string) string {
+	t := Torrent{Custom1: custom1}
+	if labels := t.Labels(); len(labels) > 0 {
+		return labels[0]
+	}
+	return ""
+}
+
+func containsString(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+// GetLabel returns the first label of the torrent, empty if it has none. Use
+// Torrent.Labels to get all of them.
+func (r *RTorrent) GetLabel(t Torrent) (string, error) {
+	return r.GetLabelContext(context.Background(), t)
+}
+
+// GetLabelContext is like GetLabel but honors ctx cancellation and deadlines
+func (r *RTorrent) GetLabelContext(ctx context.Context, t Torrent) (string, error) {
+	var custom1 string
+	if err := r.call(ctx, &custom1, "d.custom1", t.Hash); err != nil {
+		return "", err
+	}
+	return firstLabel(custom1), nil
+}
+
+// SetLabel replaces the labels of the torrent with `label`, or removes them
+// if it is empty
+func (r *RTorrent) SetLabel(t Torrent, label string) error {
+	return r.SetLabelsContext(context.Background(), t, label)
+}
+
+// SetLabelContext is like SetLabel but honors ctx cancellation and deadlines
+func (r *RTorrent) SetLabelContext(ctx context.Context, t Torrent, label string) error {
+	return r.SetLabelsContext(ctx, t, label)
+}
+
+// SetLabels replaces the labels of the torrent
+func (r *RTorrent) SetLabels(t Torrent, labels ...string) error {
+	return r.SetLabelsContext(context.Background(), t, labels...)
+}
+
+// SetLabelsContext is like SetLabels but honors ctx cancellation and deadlines
+func (r *RTorrent) SetLabelsContext(ctx context.Context, t Torrent, labels ...string) error {
+	_, err := r.transport.Call(ctx, "d.custom1.set", t.Hash, EncodeLabels(labels...))
+	if err != nil {
+		return wrapCallError("d.custom1.set", err)
+	}
+	return nil
+}
+
+// ListLabels returns the labels in use by any torrent, sorted
+func (r *RTorrent) ListLabels() ([]string, error) {
+	return r.ListLabelsContext(context.Background())
+}
+
+// ListLabelsContext is like ListLabels but honors ctx cancellation and deadlines
+func (r *RTorrent) ListLabelsContext(ctx context.Context) ([]string, error) {
+	torrents, err := r.Torrents(ViewMain).Fields(FieldCustom(1)).DoContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return collectLabels(torrents), nil
+}
+
+// collectLabels returns the distinct labels of torrents, sorted
+func collectLabels(torrents []Torrent) []string {
+	labels := []string{}
+	for _, t := range torrents {
+		for _, label := range t.Labels() {
+			if !containsString(labels, label) {
+				labels = append(labels, label)
+			}
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// GetTorrentsByLabel returns the torrents of `view` carrying `label`, or the
+// unlabeled ones if it is empty
+func (r *RTorrent) GetTorrentsByLabel(view View, label string) ([]Torrent, error) {
+	return r.GetTorrentsByLabelContext(context.Background(), view, label)
+}
+
+// GetTorrentsByLabelContext is like GetTorrentsByLabel but honors ctx
+// cancellation and deadlines
+func (r *RTorrent) GetTorrentsByLabelContext(ctx context.Context, view View, label string) ([]Torrent, error) {
+	return r.Torrents(view).Fields(torrentListFields...).Label(label).DoContext(ctx)
+}
@@ -0,0 +1,108 @@
+package rtorrent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tab1293/go-rtorrent/xmlrpc"
+)
+
+// Peer represents a peer connected to a torrent
+type Peer struct {
+	// ID is the hex encoded peer id, used to target the peer
+	ID               string `xmlrpc:"p.id="`
+	Address          string `xmlrpc:"p.address="`
+	Port             int    `xmlrpc:"p.port="`
+	ClientVersion    string `xmlrpc:"p.client_version="`
+	CompletedPercent int    `xmlrpc:"p.completed_percent="`
+	DownRate         int    `xmlrpc:"p.down_rate="`
+	UpRate           int    `xmlrpc:"p.up_rate="`
+	DownTotal        int64  `xmlrpc:"p.down_total="`
+	UpTotal          int64  `xmlrpc:"p.up_total="`
+	IsEncrypted      bool   `xmlrpc:"p.is_encrypted="`
+	IsIncoming       bool   `xmlrpc:"p.is_incoming="`
+	IsSnubbed        bool   `xmlrpc:"p.is_snubbed="`
+}
+
+// peerFields are the p.* commands fetched by GetPeers, every field of `Peer`
+var peerFields = xmlrpc.FieldNames(Peer{})
+
+// GetPeers returns the peers connected to the torrent
+func (r *RTorrent) GetPeers(t Torrent) ([]Peer, error) {
+	return r.GetPeersContext(context.Background(), t)
+}
+
+// GetPeersContext is like GetPeers but honors ctx cancellation and deadlines
+func (r *RTorrent) GetPeersContext(ctx context.Context, t Torrent) ([]Peer, error) {
+	var peers []Peer
+	if err := r.multicall(ctx, &peers, "p.multicall", []interface{}{t.Hash, ""}, peerFields); err != nil {
+		return nil, err
+	}
+	return peers, nil
+}
+
+// DisconnectPeer disconnects the peer from the torrent; it may connect again
+func (r *RTorrent) DisconnectPeer(t Torrent, p Peer) error {
+	return r.DisconnectPeerContext(context.Background(), t, p)
+}
+
+// DisconnectPeerContext is like DisconnectPeer but honors ctx cancellation and deadlines
+func (r *RTorrent) DisconnectPeerContext(ctx context.Context, t Torrent, p Peer) error {
+	_, err := r.transport.Call(ctx, "p.disconnect", fmt.Sprintf("%s:p%s", t.Hash, p.ID))
+	if err != nil {
+		return wrapCallError("p.disconnect", err)
+	}
+	return nil
+}
+
+// BanPeer bans the peer, then disconnects it, in a single round trip
+func (r *RTorrent) BanPeer(t Torrent, p Peer) error {
+	return r.BanPeerContext(context.Background(), t, p)
+}
+
+// BanPeerContext is like BanPeer but honors ctx cancellation and deadlines
+func (r *RTorrent) BanPeerContext(ctx context.Context, t Torrent, p Peer) error {
+	target := fmt.Sprintf("%s:p%s", t.Hash, p.ID)
+	calls := []Call{
+		{Method: "p.banned.set", Args: []interface{}{target, 1}},
+		{Method: "p.disconnect", Args: []interface{}{target}},
+	}
+	results, err := r.transport.Multicall(ctx, calls)
+	if err != nil {
+		return wrapCallError("system.multicall", err)
+	}
+	for i, result := range results {
+		if result.Err != nil {
+			return wrapCallError(calls[i].Method, result.Err)
+		}
+	}
+	return nil
+}
+
+// SnubPeer snubs the peer: nothing is uploaded to it anymore
+func (r *RTorrent) SnubPeer(t Torrent, p Peer) error {
+	return r.SnubPeerContext(context.Background(), t, p)
+}
+
+// SnubPeerContext is like SnubPeer but honors ctx cancellation and deadlines
+func (r *RTorrent) SnubPeerContext(ctx context.Context, t Torrent, p Peer) error {
+	return r.setPeerSnubbed(ctx, t, p, 1)
+}
+
+// UnsnubPeer lets a snubbed peer be uploaded to again
+func (r *RTorrent) UnsnubPeer(t Torrent, p Peer) error {
+	return r.UnsnubPeerContext(context.Background(), t, p)
+}
+
+// UnsnubPeerContext is like UnsnubPeer but honors ctx cancellation and deadlines
+func (r *RTorrent) UnsnubPeerContext(ctx context.Context, t Torrent, p Peer) error {
+	return r.setPeerSnubbed(ctx, t, p, 0)
+}
+
+func (r *RTorrent) setPeerSnubbed(ctx context.Context, t Torrent, p Peer, snubbed int) error {
+	_, err := r.transport.Call(ctx, "p.snubbed.set", fmt.Sprintf("%s:p%s", t.Hash, p.ID), snubbed)
+	if err != nil {
+		return wrapCallError("p.snubbed.set", err)
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package rtorrent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tab1293/go-rtorrent/xmlrpc"
+)
+
+// Field is a d.* command fetched for every torrent of a TorrentQuery, such
+// as "d.name=". Fields matching the `xmlrpc` tag of a `Torrent` member
+// populate it, any other command is returned in `Torrent.Extra`.
+type Field string
+
+// Fields populating the members of `Torrent`
+const (
+	FieldHash              Field = "d.hash="
+	FieldName              Field = "d.name="
+	FieldPath              Field = "d.base_path="
+	FieldSize              Field = "d.size_bytes="
+	FieldCompleted         Field = "d.complete="
+	FieldCompletedBytes    Field = "d.completed_bytes="
+	FieldRatio             Field = "d.ratio="
+	FieldState             Field = "d.state="
+	FieldDownRate          Field = "d.down.rate="
+	FieldUpRate            Field = "d.up.rate="
+	FieldPeersConnected    Field = "d.peers_connected="
+	FieldPeersNotConnected Field = "d.peers_not_connected="
+	FieldPeersComplete     Field = "d.peers_complete="
+	FieldPeersAccounted    Field = "d.peers_accounted="
+	FieldHashing           Field = "d.hashing="
+	FieldChunkSize         Field = "d.chunk_size="
+	FieldIsMultiFile       Field = "d.is_multi_file="
+	FieldDirectory         Field = "d.directory="
+	FieldLeftBytes         Field = "d.left_bytes="
+	FieldDownTotal         Field = "d.down.total="
+	FieldUpTotal           Field = "d.up.total="
+	FieldSizeFiles         Field = "d.size_files="
+	FieldSizeChunks        Field = "d.size_chunks="
+	FieldCompletedChunks   Field = "d.completed_chunks="
+	FieldIsOpen            Field = "d.is_open="
+	FieldIsActive          Field = "d.is_active="
+	FieldIsPrivate         Field = "d.is_private="
+	FieldIsHashChecked     Field = "d.is_hash_checked="
+	FieldIsHashChecking    Field = "d.is_hash_checking="
+	FieldPeersMin          Field = "d.peers_min="
+	FieldPeersMax          Field = "d.peers_max="
+	FieldPriority          Field = "d.priority="
+	FieldThrottleName      Field = "d.throttle_name="
+	FieldMessage           Field = "d.message="
+	FieldTiedToFile        Field = "d.tied_to_file="
+	FieldCreationDate      Field = "d.creation_date="
+	FieldLoadDate          Field = "d.load_date="
+	FieldStartedAt         Field = "d.timestamp.started="
+	FieldFinishedAt        Field = "d.timestamp.finished="
+)
+
+// FieldCustom is the d.customN command, for N from 1 to 5, populating the
+// CustomN member
+func FieldCustom(n int) Field {
+	return Field(fmt.Sprintf("d.custom%d=", n))
+}
+
+// FieldCustomKey is the d.custom command for an arbitrary `key`
+func FieldCustomKey(key string) Field {
+	return Field("d.custom=" + key)
+}
+
+// torrentMembers are the commands populating a member of `Torrent`
+var torrentMembers = func() map[string]bool {
+	members := make(map[string]bool, len(torrentFields))
+	for _, f := range torrentFields {
+		members[f] = true
+	}
+	return members
+}()
+
+// TorrentQuery lists torrents, fetching only the chosen fields
+//
+//	torrents, err := r.Torrents(rtorrent.ViewMain).
+//		Fields(rtorrent.FieldHash, rtorrent.FieldDownRate, rtorrent.FieldCustom(1)).
+//		Do()
+type TorrentQuery struct {
+	view   View
+	hashes []string
+	fields []Field
+	// label filters the torrents by label if labeled is set
+	label   string
+	labeled bool
+	run     func(ctx context.Context, q *TorrentQuery) ([]Torrent, error)
+}
+
+// Torrents starts a query over the torrents of `view`
+func (r *RTorrent) Torrents(view View) *TorrentQuery {
+	return NewTorrentQuery(view, r.runTorrentQuery)
+}
+
+// NewTorrentQuery returns a query over `view` executed by `run`. It lets
+// implementations of Client other than RTorrent provide Torrents.
+func NewTorrentQuery(view View, run func(ctx context.Context, q *TorrentQuery) ([]Torrent, error)) *TorrentQuery {
+	return &TorrentQuery{view: view, run: run}
+}
+
+// Fields adds commands to fetch for every torrent. Without any, every member
+// of `Torrent` is fetched.
+func (q *TorrentQuery) Fields(fields ...Field) *TorrentQuery {
+	q.fields = append(q.fields, fields...)
+	return q
+}
+
+// Hashes restricts the query to the torrents with the given info-hashes,
+// which are then queried directly instead of through the view; unknown
+// hashes are left out, see GetTorrentsByHash
+func (q *TorrentQuery) Hashes(hashes ...string) *TorrentQuery {
+	q.hashes = append(q.hashes, hashes...)
+	return q
+}
+
+// Label restricts the query to the torrents carrying `label`, or to the
+// unlabeled torrents if it is empty. Custom1 is fetched for that purpose.
+func (q *TorrentQuery) Label(label string) *TorrentQuery {
+	q.label, q.labeled = label, true
+	return q
+}
+
+// View returns the view the query runs on
+func (q *TorrentQuery) View() View {
+	return q.view
+}
+
+// HashFilter returns the hashes the query is restricted to, nil if it isn't
+func (q *TorrentQuery) HashFilter() []string {
+	return q.hashes
+}
+
+// LabelFilter returns the label the query is restricted to, and whether it is
+func (q *TorrentQuery) LabelFilter() (string, bool) {
+	return q.label, q.labeled
+}
+
+// SelectedFields returns the commands the query fetches
+func (q *TorrentQuery) SelectedFields() []Field {
+	if q.labeled && len(q.fields) > 0 && !containsField(q.fields, FieldCustom(1)) {
+		return append(q.fields[:len(q.fields):len(q.fields)], FieldCustom(1))
+	}
+	if len(q.fields) == 0 {
+		fields := make([]Field, len(torrentFields))
+		for i, f := range torrentFields {
+			fields[i] = Field(f)
+		}
+		return fields
+	}
+	return q.fields
+}
+
+// Do runs the query
+func (q *TorrentQuery) Do() ([]Torrent, error) {
+	return q.DoContext(context.Background())
+}
+
+// DoContext is like Do but honors ctx cancellation and deadlines
+func (q *TorrentQuery) DoContext(ctx context.Context) ([]Torrent, error) {
+	torrents, err := q.run(ctx, q)
+	if err != nil || !q.labeled {
+		return torrents, err
+	}
+	filtered := torrents[:0]
+	for _, t := range torrents {
+		if labels := t.Labels(); (q.label == "" && len(labels) == 0) || containsString(labels, q.label) {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered, nil
+}
+
+func containsField(fields []Field, field Field) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RTorrent) runTorrentQuery(ctx context.Context, q *TorrentQuery) ([]Torrent, error) {
+	selected := q.SelectedFields()
+	fields := make([]string, len(selected))
+	for i, f := range selected {
+		fields[i] = string(f)
+	}
+
+	var rows []interface{}
+	var err error
+	method := "d.multicall2"
+	if q.hashes != nil {
+		method = "system.multicall"
+		rows, err = r.torrentRowsByHash(ctx, q.hashes, fields)
+	} else {
+		rows, err = r.torrentRows(ctx, q.view, fields)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeTorrents(method, rows, fields)
+}
+
+// decodeTorrents decodes d.* command rows into torrents, collecting the
+// commands that don't populate a member into Extra
+func decodeTorrents(method string, rows []interface{}, fields []string) ([]Torrent, error) {
+	var torrents []Torrent
+	if err := decodeError(method, xmlrpc.DecodeMulticall(rows, fields, &torrents)); err != nil {
+		return nil, err
+	}
+	for i := range torrents {
+		values := rows[i].([]interface{})
+		for j, field := range fields {
+			if torrentMembers[field] {
+				continue
+			}
+			if torrents[i].Extra == nil {
+				torrents[i].Extra = make(map[string]interface{})
+			}
+			torrents[i].Extra[field] = values[j]
+		}
+		// d.ratio is reported in thousandths
+		torrents[i].Ratio /= 1000
+		torrents[i].Label = firstLabel(torrents[i].Custom1)
+	}
+	return torrents, nil
+}
@@ -0,0 +1,150 @@
+package rtorrent
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// cannedServer answers every XML-RPC request with the given raw response body
+func cannedServer(t *testing.T, body string) *RTorrent {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return New(srv.URL, false)
+}
+
+func response(value string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?><methodResponse><params><param><value>` +
+		value + `</value></param></params></methodResponse>`
+}
+
+func TestCannedResponses(t *testing.T) {
+	t.Run("get torrents", func(t *testing.T) {
+		client := cannedServer(t, response(`<array><data>
+			<value><array><data>
+				<value><string>ubuntu-19.04-live-server-amd64.iso</string></value>
+				<value><i8>784334848</i8></value>
+				<value><string>B7B0FBAB74A85D4AC170662C645982A862826455</string></value>
+				<value><string></string></value>
+				<value><string>/downloads/incoming/ubuntu-19.04-live-server-amd64.iso</string></value>
+				<value><i8>1</i8></value>
+				<value><i8>0</i8></value>
+				<value><i8>1500</i8></value>
+			</data></array></value>
+		</data></array>`))
+		torrents, err := client.GetTorrents(ViewMain)
+		require.NoError(t, err)
+		require.Len(t, torrents, 1)
+		require.Equal(t, "B7B0FBAB74A85D4AC170662C645982A862826455", torrents[0].Hash)
+		require.Equal(t, "ubuntu-19.04-live-server-amd64.iso", torrents[0].Name)
+		require.EqualValues(t, 784334848, torrents[0].Size)
+		require.False(t, torrents[0].Completed)
+		require.Equal(t, 1.5, torrents[0].Ratio)
+	})
+
+	t.Run("get torrents with a wrongly typed field", func(t *testing.T) {
+		client := cannedServer(t, response(`<array><data>
+			<value><array><data>
+				<value><string>name</string></value>
+				<value><string>not a size</string></value>
+				<value><string>HASH</string></value>
+				<value><string></string></value>
+				<value><string>/path</string></value>
+				<value><i8>1</i8></value>
+				<value><i8>0</i8></value>
+				<value><i8>0</i8></value>
+			</data></array></value>
+		</data></array>`))
+		_, err := client.GetTorrents(ViewMain)
+		require.Error(t, err)
+		unexpected, ok := err.(*UnexpectedResponseError)
+		require.True(t, ok, "got %T: %v", err, err)
+		require.Equal(t, "d.multicall2", unexpected.Method)
+		require.Equal(t, "d.size_bytes=", unexpected.Field)
+		require.Equal(t, "not a size", unexpected.Value)
+	})
+
+	t.Run("get torrents with a short row", func(t *testing.T) {
+		client := cannedServer(t, response(`<array><data>
+			<value><array><data><value><string>name</string></value></data></array></value>
+		</data></array>`))
+		_, err := client.GetTorrents(ViewMain)
+		require.IsType(t, &UnexpectedResponseError{}, err)
+	})
+
+	t.Run("get torrents with no rows", func(t *testing.T) {
+		client := cannedServer(t, response(`<string>oops</string>`))
+		_, err := client.GetTorrents(ViewMain)
+		require.IsType(t, &UnexpectedResponseError{}, err)
+	})
+
+	t.Run("get files", func(t *testing.T) {
+		client := cannedServer(t, response(`<array><data>
+			<value><array><data>
+				<value><string>a.iso</string></value>
+				<value><i8>100</i8></value>
+				<value><i8>1</i8></value>
+				<value><i8>4</i8></value>
+				<value><i8>2</i8></value>
+			</data></array></value>
+			<value><array><data>
+				<value><string>b.nfo</string></value>
+				<value><i8>10</i8></value>
+				<value><i8>0</i8></value>
+				<value><i8>1</i8></value>
+				<value><i8>1</i8></value>
+			</data></array></value>
+		</data></array>`))
+		files, err := client.GetFiles(Torrent{Hash: "HASH"})
+		require.NoError(t, err)
+		require.Len(t, files, 2)
+		require.Equal(t, "b.nfo", files[1].Path)
+		require.Equal(t, 1, files[1].Index)
+		require.Equal(t, 4, files[0].TotalChunks)
+		require.Equal(t, 2, files[0].ChunksCompleted)
+	})
+
+	t.Run("totals", func(t *testing.T) {
+		client := cannedServer(t, response(`<i8>1234</i8>`))
+		total, err := client.DownTotal()
+		require.NoError(t, err)
+		require.EqualValues(t, 1234, total)
+	})
+
+	t.Run("string where an int was expected", func(t *testing.T) {
+		client := cannedServer(t, response(`<string>1234</string>`))
+		_, err := client.UpRate()
+		unexpected, ok := err.(*UnexpectedResponseError)
+		require.True(t, ok, "got %T: %v", err, err)
+		require.Equal(t, "throttle.global_up.rate", unexpected.Method)
+		require.Equal(t, "1234", unexpected.Value)
+	})
+
+	t.Run("empty params", func(t *testing.T) {
+		client := cannedServer(t, `<?xml version="1.0"?><methodResponse><params></params></methodResponse>`)
+		_, err := client.IP()
+		require.IsType(t, &UnexpectedResponseError{}, err)
+	})
+
+	t.Run("list methods", func(t *testing.T) {
+		client := cannedServer(t, response(`<array><data>
+			<value><string>d.name</string></value>
+			<value><string>d.hash</string></value>
+		</data></array>`))
+		methods, err := client.ListMethods()
+		require.NoError(t, err)
+		require.Equal(t, []string{"d.name", "d.hash"}, methods)
+	})
+
+	t.Run("list methods with a non-string", func(t *testing.T) {
+		client := cannedServer(t, response(`<array><data><value><i4>1</i4></value></data></array>`))
+		_, err := client.ListMethods()
+		require.IsType(t, &UnexpectedResponseError{}, err)
+	})
+}
@@ -1,52 +1,135 @@
 package rtorrent
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/tab1293/go-rtorrent/jsonrpc"
+	"github.com/tab1293/go-rtorrent/metainfo"
 	"github.com/tab1293/go-rtorrent/xmlrpc"
 )
 
 // RTorrent is used to communicate with a remote rTorrent instance
 type RTorrent struct {
-	addr         string
-	xmlrpcClient *xmlrpc.Client
+	addr      string
+	transport Transport
 }
 
 // Torrent represents a torrent in rTorrent
+// The `xmlrpc` tags name the d.* command each field is fetched with
 type Torrent struct {
-	Hash              string
-	Name              string
-	Path              string
-	Size              int
-	Completed         bool
-	CompletedBytes    int
-	Ratio             float64
-	State             int
-	DownRate          int
-	UpRate            int
-	PeersConnected    int
-	PeersNotConnected int
-	PeersComplete     int
-	PeersAccounted    int
-	Hashing           int
-	ChunkSize         int
-	IsMultiFile       bool
+	Hash              string  `xmlrpc:"d.hash="`
+	Name              string  `xmlrpc:"d.name="`
+	Path              string  `xmlrpc:"d.base_path="`
+	Size              int64   `xmlrpc:"d.size_bytes="`
+	Completed         bool    `xmlrpc:"d.complete="`
+	CompletedBytes    int64   `xmlrpc:"d.completed_bytes="`
+	Ratio             float64 `xmlrpc:"d.ratio="`
+	State             int     `xmlrpc:"d.state="`
+	DownRate          int     `xmlrpc:"d.down.rate="`
+	UpRate            int     `xmlrpc:"d.up.rate="`
+	PeersConnected    int     `xmlrpc:"d.peers_connected="`
+	PeersNotConnected int     `xmlrpc:"d.peers_not_connected="`
+	PeersComplete     int     `xmlrpc:"d.peers_complete="`
+	PeersAccounted    int     `xmlrpc:"d.peers_accounted="`
+	Hashing           int     `xmlrpc:"d.hashing="`
+	ChunkSize         int     `xmlrpc:"d.chunk_size="`
+	IsMultiFile       bool    `xmlrpc:"d.is_multi_file="`
+
+	Directory       string    `xmlrpc:"d.directory="`
+	LeftBytes       int64     `xmlrpc:"d.left_bytes="`
+	DownTotal       int64     `xmlrpc:"d.down.total="`
+	UpTotal         int64     `xmlrpc:"d.up.total="`
+	SizeFiles       int       `xmlrpc:"d.size_files="`
+	SizeChunks      int       `xmlrpc:"d.size_chunks="`
+	CompletedChunks int       `xmlrpc:"d.completed_chunks="`
+	IsOpen          bool      `xmlrpc:"d.is_open="`
+	IsActive        bool      `xmlrpc:"d.is_active="`
+	IsPrivate       bool      `xmlrpc:"d.is_private="`
+	IsHashChecked   bool      `xmlrpc:"d.is_hash_checked="`
+	IsHashChecking  bool      `xmlrpc:"d.is_hash_checking="`
+	PeersMin        int       `xmlrpc:"d.peers_min="`
+	PeersMax        int       `xmlrpc:"d.peers_max="`
+	Priority        Priority  `xmlrpc:"d.priority="`
+	ThrottleName    string    `xmlrpc:"d.throttle_name="`
+	Message         string    `xmlrpc:"d.message="`
+	Custom1         string    `xmlrpc:"d.custom1="`
+	Custom2         string    `xmlrpc:"d.custom2="`
+	Custom3         string    `xmlrpc:"d.custom3="`
+	Custom4         string    `xmlrpc:"d.custom4="`
+	Custom5         string    `xmlrpc:"d.custom5="`
+	TiedToFile      string    `xmlrpc:"d.tied_to_file="`
+	CreationDate    time.Time `xmlrpc:"d.creation_date="`
+	LoadDate        time.Time `xmlrpc:"d.load_date="`
+	StartedAt       time.Time `xmlrpc:"d.timestamp.started="`
+	FinishedAt      time.Time `xmlrpc:"d.timestamp.finished="`
+
+	// Label is the first of the labels stored in Custom1 by ruTorrent, as
+	// most torrents have a single one; see Labels for all of them
+	Label string `xmlrpc:"-"`
+
+	// Extra holds the values of the commands fetched by a TorrentQuery that
+	// don't populate any other member, by command
+	Extra map[string]interface{} `xmlrpc:"-" json:",omitempty"`
+}
+
+// Priority is the download priority of a torrent, d.priority
+type Priority int
+
+const (
+	// PriorityOff doesn't download the torrent at all
+	PriorityOff Priority = 0
+	// PriorityLow downloads the torrent after the others
+	PriorityLow Priority = 1
+	// PriorityNormal is the priority torrents are added with
+	PriorityNormal Priority = 2
+	// PriorityHigh downloads the torrent before the others
+	PriorityHigh Priority = 3
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityOff:
+		return "off"
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	}
+	return fmt.Sprintf("Priority(%d)", int(p))
 }
 
 // File represents a file in rTorrent
+// The `xmlrpc` tags name the f.* command each field is fetched with
 type File struct {
-	Path            string
-	Size            int
-	Priority        int
-	Index           int
-	TotalChunks     int
-	ChunksCompleted int
+	Path            string `xmlrpc:"f.path="`
+	Size            int64  `xmlrpc:"f.size_bytes="`
+	Priority        int    `xmlrpc:"f.priority="`
+	Index           int    `xmlrpc:"-"`
+	TotalChunks     int    `xmlrpc:"f.size_chunks="`
+	ChunksCompleted int    `xmlrpc:"f.completed_chunks="`
 }
 
-// View represents a "view" within RTorrent
+// torrentListFields are the d.* commands fetched by GetTorrents
+var torrentListFields = []Field{FieldName, FieldSize, FieldHash, FieldCustom(1), FieldPath, FieldIsActive, FieldCompleted, FieldRatio}
+
+// torrentFields are the d.* commands fetched by GetTorrent, every field of `Torrent`
+var torrentFields = xmlrpc.FieldNames(Torrent{})
+
+// fileFields are the f.* commands fetched by GetFiles, every field of `File`
+var fileFields = xmlrpc.FieldNames(File{})
+
+// View represents a "view" within RTorrent. Besides the built-in views
+// below, custom views created with CreateView or in rtorrent.rc can be used
+// wherever a View is expected.
 type View string
 
 const (
@@ -76,311 +159,497 @@ func (f *File) Pretty() string {
 
 // New returns a new instance of `RTorrent`
 // Pass in a true value for `insecure` to turn off certificate verification
+// `addr` is either an HTTP(S) URL such as "http://localhost/RPC2", or an SCGI
+// endpoint: "scgi:///path/to/rpc.socket" or "scgi://host:port"
+// Prefix `addr` with "jsonrpc+", e.g. "jsonrpc+scgi://host:port", to talk
+// JSON-RPC to rTorrent 0.15 and later instead of XML-RPC
 func New(addr string, insecure bool) *RTorrent {
+	if jsonrpc.IsJSONRPC(addr) {
+		return &RTorrent{
+			addr:      addr,
+			transport: NewJSONRPCTransport(jsonrpc.NewClient(addr, insecure)),
+		}
+	}
 	return &RTorrent{
-		addr:         addr,
-		xmlrpcClient: xmlrpc.NewClient(addr, insecure),
+		addr:      addr,
+		transport: NewXMLRPCTransport(xmlrpc.NewClient(addr, insecure)),
 	}
 }
 
+// NewWithTransport returns a new instance of `RTorrent` sending its commands
+// through `transport`, see NewXMLRPCTransport and NewJSONRPCTransport
+func NewWithTransport(transport Transport) *RTorrent {
+	return &RTorrent{transport: transport}
+}
+
 // WithHTTPClient allows you to a provide a custom http.Client.
+// It has no effect on an `RTorrent` created with NewWithTransport.
 func (r *RTorrent) WithHTTPClient(client *http.Client) *RTorrent {
-	r.xmlrpcClient = xmlrpc.NewClientWithHTTPClient(r.addr, client)
+	switch r.transport.(type) {
+	case *xmlrpcTransport:
+		r.transport = NewXMLRPCTransport(xmlrpc.NewClientWithHTTPClient(r.addr, client))
+	case *jsonrpcTransport:
+		r.transport = NewJSONRPCTransport(jsonrpc.NewClientWithHTTPClient(r.addr, client))
+	}
 	return r
 }
 
 func (r *RTorrent) Shutdown() error {
-	_, err := r.xmlrpcClient.Call("system.shutdown.normal")
+	return r.ShutdownContext(context.Background())
+}
+
+// ShutdownContext is like Shutdown but honors ctx cancellation and deadlines
+func (r *RTorrent) ShutdownContext(ctx context.Context) error {
+	_, err := r.transport.Call(ctx, "system.shutdown.normal")
 	if err != nil {
-		return errors.Wrap(err, "system.shutdown.normal XMLRPC call failed")
+		return wrapCallError("system.shutdown.normal", err)
 	}
 
 	return nil
 }
 
 func (r *RTorrent) SetSessionDirectory(d string) error {
-	fmt.Printf("setting session path: %s\n", d)
-	_, err := r.xmlrpcClient.Call("session.path.set", d)
+	return r.SetSessionDirectoryContext(context.Background(), d)
+}
+
+// SetSessionDirectoryContext is like SetSessionDirectory but honors ctx cancellation and deadlines
+func (r *RTorrent) SetSessionDirectoryContext(ctx context.Context, d string) error {
+	_, err := r.transport.Call(ctx, "session.path.set", d)
 	if err != nil {
-		return errors.Wrap(err, "session.path.set XMLRPC call failed")
+		return wrapCallError("session.path.set", err)
 	}
 	return nil
 }
 
 func (r *RTorrent) SetDefaultDirectory(t Torrent, d string) error {
-	fmt.Printf("setting default directory: %s\n", d)
-	_, err := r.xmlrpcClient.Call("d.directory.set", t.Hash, d)
+	return r.SetDefaultDirectoryContext(context.Background(), t, d)
+}
+
+// SetDefaultDirectoryContext is like SetDefaultDirectory but honors ctx cancellation and deadlines
+func (r *RTorrent) SetDefaultDirectoryContext(ctx context.Context, t Torrent, d string) error {
+	_, err := r.transport.Call(ctx, "d.directory.set", t.Hash, d)
 	if err != nil {
-		return errors.Wrap(err, "d.directory.set XMLRPC call failed")
+		return wrapCallError("d.directory.set", err)
 	}
 	return nil
 }
 
 // GetTorrent returns the torrent identified by the given hash
 func (r *RTorrent) GetTorrent(t Torrent) (Torrent, error) {
-	var ret Torrent
-	args := []interface{}{"", string(ViewMain), "d.hash=", "d.complete=", "d.completed_bytes=", "d.down.rate=", "d.up.rate=", "d.ratio=", "d.size_bytes=", "d.state=", "d.peers_connected=", "d.name=", "d.base_path=", "d.hashing=", "d.chunk_size=", "d.peers_not_connected=", "d.peers_accounted=", "d.peers_complete=", "d.is_multi_file="}
-	results, err := r.xmlrpcClient.Call("d.multicall2", args...)
+	return r.GetTorrentContext(context.Background(), t)
+}
+
+// GetTorrentContext is like GetTorrent but honors ctx cancellation and deadlines
+func (r *RTorrent) GetTorrentContext(ctx context.Context, t Torrent) (Torrent, error) {
+	torrents, err := r.Torrents(ViewMain).Hashes(t.Hash).DoContext(ctx)
+	if err != nil {
+		return Torrent{}, err
+	}
+	if len(torrents) == 0 {
+		return Torrent{}, ErrTorrentNotFound
+	}
+	return torrents[0], nil
+}
+
+// GetTorrentsByHash returns the torrents identified by the given hashes, in
+// the same order; unknown hashes are left out.
+// Only these torrents are queried, all in a single system.multicall.
+func (r *RTorrent) GetTorrentsByHash(hashes ...string) ([]Torrent, error) {
+	return r.GetTorrentsByHashContext(context.Background(), hashes...)
+}
+
+// GetTorrentsByHashContext is like GetTorrentsByHash but honors ctx cancellation and deadlines
+func (r *RTorrent) GetTorrentsByHashContext(ctx context.Context, hashes ...string) ([]Torrent, error) {
+	if len(hashes) == 0 {
+		return []Torrent{}, nil
+	}
+	return r.Torrents(ViewMain).Hashes(hashes...).DoContext(ctx)
+}
+
+// torrentRowsByHash calls each of the given d.* commands on each of
+// `hashes`, leaving out the torrents rTorrent doesn't know. Commands this
+// rTorrent doesn't have, as older versions lack some, are left nil, so that
+// their members keep their zero value.
+func (r *RTorrent) torrentRowsByHash(ctx context.Context, hashes []string, fields []string) ([]interface{}, error) {
+	calls := make([]Call, 0, len(hashes)*len(fields))
+	for _, hash := range hashes {
+		for _, field := range fields {
+			name, args := splitCommand(field)
+			calls = append(calls, Call{Method: name, Args: append([]interface{}{hash}, args...)})
+		}
+	}
+	results, err := r.transport.Multicall(ctx, calls)
 	if err != nil {
-		return ret, err
-	}
-	for _, outerResult := range results.([]interface{}) {
-		for _, innerResult := range outerResult.([]interface{}) {
-			d := innerResult.([]interface{})
-			hash := d[0].(string)
-			if hash == t.Hash {
-				ret.Hash = hash
-				ret.Completed = d[1].(int) > 0
-				ret.CompletedBytes = d[2].(int)
-				ret.DownRate = d[3].(int)
-				ret.UpRate = d[4].(int)
-				ret.Ratio = float64(d[5].(int)) / float64(1000)
-				ret.Size = d[6].(int)
-				ret.State = d[7].(int)
-				ret.PeersConnected = d[8].(int)
-				ret.Name = d[9].(string)
-				ret.Path = d[10].(string)
-				ret.Hashing = d[11].(int)
-				ret.ChunkSize = d[12].(int)
-				ret.PeersNotConnected = d[13].(int)
-				ret.PeersAccounted = d[14].(int)
-				ret.PeersComplete = d[15].(int)
-				ret.IsMultiFile = d[16].(int) > 0
-				return ret, nil
+		return nil, wrapCallError("system.multicall", err)
+	}
+
+	rows := make([]interface{}, 0, len(hashes))
+	for i := range hashes {
+		row := make([]interface{}, len(fields))
+		for j := range fields {
+			result := results[i*len(fields)+j]
+			if result.Err != nil {
+				err := wrapCallError(calls[i*len(fields)+j].Method, result.Err)
+				if errors.Is(err, ErrMethodNotFound) {
+					continue
+				}
+				row = nil
+				if errors.Is(err, ErrTorrentNotFound) {
+					break
+				}
+				return nil, err
 			}
+			row[j] = result.Value
 		}
+		if row != nil {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// splitCommand splits a multicall command such as "d.custom=key" into the
+// method and the arguments it takes when called on its own
+func splitCommand(cmd string) (string, []interface{}) {
+	parts := strings.SplitN(cmd, "=", 2)
+	if len(parts) == 1 || parts[1] == "" {
+		return parts[0], nil
+	}
+	var args []interface{}
+	for _, arg := range strings.Split(parts[1], ",") {
+		args = append(args, arg)
 	}
-	return ret, errors.New("torrent not found")
+	return parts[0], args
 }
 
 // AddTorrentURL adds a new torrent by URL
 func (r *RTorrent) AddTorrentURL(url string) error {
-	_, err := r.xmlrpcClient.Call("load.normal", "", url)
+	return r.AddTorrentURLContext(context.Background(), url)
+}
+
+// AddTorrentURLContext is like AddTorrentURL but honors ctx cancellation and deadlines
+func (r *RTorrent) AddTorrentURLContext(ctx context.Context, url string) error {
+	_, err := r.transport.Call(ctx, "load.normal", "", url)
 	if err != nil {
-		return errors.Wrap(err, "load.normal XMLRPC call failed")
+		return wrapCallError("load.normal", err)
 	}
 	return nil
 }
 
-// AddTorrent adds a new torrent by the torrent files data
+// AddTorrent adds a new torrent by the torrent files data. Its info
+// dictionary is checked with metainfo.Parse first, and isn't sent if invalid.
 func (r *RTorrent) AddTorrent(data []byte) error {
-	_, err := r.xmlrpcClient.Call("load.raw", "", data)
+	return r.AddTorrentContext(context.Background(), data)
+}
+
+// AddTorrentContext is like AddTorrent but honors ctx cancellation and deadlines
+func (r *RTorrent) AddTorrentContext(ctx context.Context, data []byte) error {
+	if _, err := metainfo.Parse(data); err != nil {
+		return errors.Wrap(err, "invalid torrent")
+	}
+	_, err := r.transport.Call(ctx, "load.raw", "", data)
 	if err != nil {
-		return errors.Wrap(err, "load.raw XMLRPC call failed")
+		return wrapCallError("load.raw", err)
 	}
 	return nil
 }
 
 func (r *RTorrent) StartTorrent(t Torrent) error {
-	_, err := r.xmlrpcClient.Call("d.start", t.Hash)
+	return r.StartTorrentContext(context.Background(), t)
+}
+
+// StartTorrentContext is like StartTorrent but honors ctx cancellation and deadlines
+func (r *RTorrent) StartTorrentContext(ctx context.Context, t Torrent) error {
+	_, err := r.transport.Call(ctx, "d.start", t.Hash)
 	if err != nil {
-		return errors.Wrap(err, "d.start XMLRPC call failed")
+		return wrapCallError("d.start", err)
 	}
 	return nil
 }
 
 func (r *RTorrent) StopTorrent(t Torrent) error {
-	_, err := r.xmlrpcClient.Call("d.stop", t.Hash)
+	return r.StopTorrentContext(context.Background(), t)
+}
+
+// StopTorrentContext is like StopTorrent but honors ctx cancellation and deadlines
+func (r *RTorrent) StopTorrentContext(ctx context.Context, t Torrent) error {
+	_, err := r.transport.Call(ctx, "d.stop", t.Hash)
 	if err != nil {
-		return errors.Wrap(err, "d.stop XMLRPC call failed")
+		return wrapCallError("d.stop", err)
 	}
 	return nil
 }
 
 func (r *RTorrent) CloseTorrent(t Torrent) error {
-	_, err := r.xmlrpcClient.Call("d.close", t.Hash)
+	return r.CloseTorrentContext(context.Background(), t)
+}
+
+// CloseTorrentContext is like CloseTorrent but honors ctx cancellation and deadlines
+func (r *RTorrent) CloseTorrentContext(ctx context.Context, t Torrent) error {
+	_, err := r.transport.Call(ctx, "d.close", t.Hash)
 	if err != nil {
-		return errors.Wrap(err, "d.close XMLRPC call failed")
+		return wrapCallError("d.close", err)
 	}
 	return nil
 }
 
 // Delete removes the torrent
 func (r *RTorrent) Delete(t Torrent) error {
-	_, err := r.xmlrpcClient.Call("d.erase", t.Hash)
+	return r.DeleteContext(context.Background(), t)
+}
+
+// DeleteContext is like Delete but honors ctx cancellation and deadlines
+func (r *RTorrent) DeleteContext(ctx context.Context, t Torrent) error {
+	_, err := r.transport.Call(ctx, "d.erase", t.Hash)
 	if err != nil {
-		return errors.Wrap(err, "d.erase XMLRPC call failed")
+		return wrapCallError("d.erase", err)
 	}
 	return nil
 }
 
 // GetTorrents returns all of the torrents reported by this RTorrent instance
 func (r *RTorrent) GetTorrents(view View) ([]Torrent, error) {
-	args := []interface{}{"", string(view), "d.name=", "d.size_bytes=", "d.hash=", "d.custom1=", "d.base_path=", "d.is_active=", "d.complete=", "d.ratio="}
-	results, err := r.xmlrpcClient.Call("d.multicall2", args...)
-	var torrents []Torrent
+	return r.GetTorrentsContext(context.Background(), view)
+}
+
+// GetTorrentsContext is like GetTorrents but honors ctx cancellation and deadlines
+func (r *RTorrent) GetTorrentsContext(ctx context.Context, view View) ([]Torrent, error) {
+	return r.Torrents(view).Fields(torrentListFields...).DoContext(ctx)
+}
+
+// torrentRows runs d.multicall2 over `view` with the given d.* commands
+func (r *RTorrent) torrentRows(ctx context.Context, view View, fields []string) ([]interface{}, error) {
+	args := []interface{}{"", string(view)}
+	for _, field := range fields {
+		args = append(args, field)
+	}
+	rows, err := r.transport.Call(ctx, "d.multicall2", args...)
 	if err != nil {
-		return torrents, errors.Wrap(err, "d.multicall2 XMLRPC call failed")
-	}
-	for _, outerResult := range results.([]interface{}) {
-		for _, innerResult := range outerResult.([]interface{}) {
-			torrentData := innerResult.([]interface{})
-			torrents = append(torrents, Torrent{
-				Hash:      torrentData[2].(string),
-				Name:      torrentData[0].(string),
-				Path:      torrentData[4].(string),
-				Size:      torrentData[1].(int),
-				Completed: torrentData[6].(int) > 0,
-				Ratio:     float64(torrentData[7].(int)) / float64(1000),
-			})
-		}
+		return nil, wrapCallError("d.multicall2", err)
 	}
-	return torrents, nil
+	list, ok := rows.([]interface{})
+	if !ok {
+		return nil, &UnexpectedResponseError{Method: "d.multicall2", Value: rows, Expected: "an array of rows"}
+	}
+	return list, nil
 }
 
 // GetFiles returns all of the files for a given `Torrent`
 func (r *RTorrent) GetFiles(t Torrent) ([]File, error) {
-	args := []interface{}{t.Hash, 0, "f.path=", "f.size_bytes=", "f.priority=", "f.completed_chunks=", "f.size_chunks="}
-	results, err := r.xmlrpcClient.Call("f.multicall", args...)
+	return r.GetFilesContext(context.Background(), t)
+}
+
+// GetFilesContext is like GetFiles but honors ctx cancellation and deadlines
+func (r *RTorrent) GetFilesContext(ctx context.Context, t Torrent) ([]File, error) {
 	var files []File
-	if err != nil {
-		return files, errors.Wrap(err, "f.multicall XMLRPC call failed")
-	}
-	for _, outerResult := range results.([]interface{}) {
-		for i, innerResult := range outerResult.([]interface{}) {
-			fileData := innerResult.([]interface{})
-			files = append(files, File{
-				Path:            fileData[0].(string),
-				Size:            fileData[1].(int),
-				Priority:        fileData[2].(int),
-				Index:           i,
-				ChunksCompleted: fileData[3].(int),
-				TotalChunks:     fileData[4].(int),
-			})
-		}
+	if err := r.multicall(ctx, &files, "f.multicall", []interface{}{t.Hash, 0}, fileFields); err != nil {
+		return nil, err
+	}
+	for i := range files {
+		files[i].Index = i
 	}
 	return files, nil
 }
 
 func (r *RTorrent) SetFilePrority(t Torrent, i int, p int) error {
-	_, err := r.xmlrpcClient.Call("f.priority.set", fmt.Sprintf("%s:f%d", t.Hash, i), p)
+	return r.SetFileProrityContext(context.Background(), t, i, p)
+}
+
+// SetFileProrityContext is like SetFilePrority but honors ctx cancellation and deadlines
+func (r *RTorrent) SetFileProrityContext(ctx context.Context, t Torrent, i int, p int) error {
+	_, err := r.transport.Call(ctx, "f.priority.set", fmt.Sprintf("%s:f%d", t.Hash, i), p)
 	if err != nil {
-		return errors.Wrap(err, "f.priority.set XMLRPC call failed")
+		return wrapCallError("f.priority.set", err)
 	}
 	return nil
 }
 
-// DownTotal returns the total downloaded metric reported by this RTorrent instance (bytes)
-func (r *RTorrent) DownTotal() (int, error) {
-	result, err := r.xmlrpcClient.Call("throttle.global_down.total")
+// SetFilePriorities sets the priority of several files of a `Torrent` at once,
+// `priorities` maps file indexes to their new priority.
+// All changes are sent in a single system.multicall.
+func (r *RTorrent) SetFilePriorities(t Torrent, priorities map[int]int) error {
+	return r.SetFilePrioritiesContext(context.Background(), t, priorities)
+}
+
+// SetFilePrioritiesContext is like SetFilePriorities but honors ctx cancellation and deadlines
+func (r *RTorrent) SetFilePrioritiesContext(ctx context.Context, t Torrent, priorities map[int]int) error {
+	indexes := make([]int, 0, len(priorities))
+	for i := range priorities {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	calls := make([]Call, 0, len(indexes)+1)
+	for _, i := range indexes {
+		calls = append(calls, Call{Method: "f.priority.set", Args: []interface{}{fmt.Sprintf("%s:f%d", t.Hash, i), priorities[i]}})
+	}
+	calls = append(calls, Call{Method: "d.update_priorities", Args: []interface{}{t.Hash}})
+
+	results, err := r.transport.Multicall(ctx, calls)
 	if err != nil {
-		return 0, errors.Wrap(err, "throttle.global_down.total XMLRPC call failed")
+		return wrapCallError("system.multicall", err)
 	}
-	if totals, ok := result.([]interface{}); ok {
-		result = totals[0]
+	for i, result := range results {
+		if result.Err == nil {
+			continue
+		}
+		if i == len(indexes) {
+			return wrapCallError("d.update_priorities", result.Err)
+		}
+		return errors.Wrapf(wrapCallError("f.priority.set", result.Err), "file %d", indexes[i])
 	}
-	if total, ok := result.(int); ok {
-		return total, nil
+	return nil
+}
+
+// SetAllFilePriorities sets the priority of every file of a `Torrent`
+func (r *RTorrent) SetAllFilePriorities(t Torrent, p int) error {
+	return r.SetAllFilePrioritiesContext(context.Background(), t, p)
+}
+
+// SetAllFilePrioritiesContext is like SetAllFilePriorities but honors ctx cancellation and deadlines
+func (r *RTorrent) SetAllFilePrioritiesContext(ctx context.Context, t Torrent, p int) error {
+	var count int
+	if err := r.call(ctx, &count, "d.size_files", t.Hash); err != nil {
+		return err
+	}
+
+	priorities := make(map[int]int, count)
+	for i := 0; i < count; i++ {
+		priorities[i] = p
 	}
-	return 0, errors.Errorf("result isn't int: %v", result)
+	return r.SetFilePrioritiesContext(ctx, t, priorities)
+}
+
+// DownTotal returns the total downloaded metric reported by this RTorrent instance (bytes)
+func (r *RTorrent) DownTotal() (int64, error) {
+	return r.DownTotalContext(context.Background())
+}
+
+// DownTotalContext is like DownTotal but honors ctx cancellation and deadlines
+func (r *RTorrent) DownTotalContext(ctx context.Context) (int64, error) {
+	var total int64
+	err := r.call(ctx, &total, "throttle.global_down.total")
+	return total, err
 }
 
 // DownRate returns the current download rate reported by this RTorrent instance (bytes/s)
 func (r *RTorrent) DownRate() (int, error) {
-	result, err := r.xmlrpcClient.Call("throttle.global_down.rate")
-	if err != nil {
-		return 0, errors.Wrap(err, "throttle.global_down.rate XMLRPC call failed")
-	}
-	if totals, ok := result.([]interface{}); ok {
-		result = totals[0]
-	}
-	if total, ok := result.(int); ok {
-		return total, nil
-	}
-	return 0, errors.Errorf("result isn't int: %v", result)
+	return r.DownRateContext(context.Background())
+}
+
+// DownRateContext is like DownRate but honors ctx cancellation and deadlines
+func (r *RTorrent) DownRateContext(ctx context.Context) (int, error) {
+	var rate int
+	err := r.call(ctx, &rate, "throttle.global_down.rate")
+	return rate, err
 }
 
 // UpTotal returns the total uploaded metric reported by this RTorrent instance (bytes)
-func (r *RTorrent) UpTotal() (int, error) {
-	result, err := r.xmlrpcClient.Call("throttle.global_up.total")
-	if err != nil {
-		return 0, errors.Wrap(err, "throttle.global_up.total XMLRPC call failed")
-	}
-	if totals, ok := result.([]interface{}); ok {
-		result = totals[0]
-	}
-	if total, ok := result.(int); ok {
-		return total, nil
-	}
-	return 0, errors.Errorf("result isn't int: %v", result)
+func (r *RTorrent) UpTotal() (int64, error) {
+	return r.UpTotalContext(context.Background())
+}
+
+// UpTotalContext is like UpTotal but honors ctx cancellation and deadlines
+func (r *RTorrent) UpTotalContext(ctx context.Context) (int64, error) {
+	var total int64
+	err := r.call(ctx, &total, "throttle.global_up.total")
+	return total, err
 }
 
 // UpRate returns the current upload rate reported by this RTorrent instance (bytes/s)
 func (r *RTorrent) UpRate() (int, error) {
-	result, err := r.xmlrpcClient.Call("throttle.global_up.rate")
-	if err != nil {
-		return 0, errors.Wrap(err, "throttle.global_up.rate XMLRPC call failed")
-	}
-	if totals, ok := result.([]interface{}); ok {
-		result = totals[0]
-	}
-	if total, ok := result.(int); ok {
-		return total, nil
-	}
-	return 0, errors.Errorf("result isn't int: %v", result)
+	return r.UpRateContext(context.Background())
+}
+
+// UpRateContext is like UpRate but honors ctx cancellation and deadlines
+func (r *RTorrent) UpRateContext(ctx context.Context) (int, error) {
+	var rate int
+	err := r.call(ctx, &rate, "throttle.global_up.rate")
+	return rate, err
 }
 
 // IP returns the IP reported by this RTorrent instance
 func (r *RTorrent) IP() (string, error) {
-	result, err := r.xmlrpcClient.Call("network.bind_address")
-	if err != nil {
-		return "", errors.Wrap(err, "network.bind_address XMLRPC call failed")
-	}
-	if ips, ok := result.([]interface{}); ok {
-		result = ips[0]
-	}
-	if ip, ok := result.(string); ok {
-		return ip, nil
-	}
-	return "", errors.Errorf("result isn't string: %v", result)
+	return r.IPContext(context.Background())
+}
+
+// IPContext is like IP but honors ctx cancellation and deadlines
+func (r *RTorrent) IPContext(ctx context.Context) (string, error) {
+	var ip string
+	err := r.call(ctx, &ip, "network.bind_address")
+	return ip, err
 }
 
 // Name returns the name reported by this RTorrent instance
 func (r *RTorrent) Name() (string, error) {
-	result, err := r.xmlrpcClient.Call("system.hostname")
-	if err != nil {
-		return "", errors.Wrap(err, "system.hostname XMLRPC call failed")
-	}
-	if names, ok := result.([]interface{}); ok {
-		result = names[0]
-	}
-	if name, ok := result.(string); ok {
-		return name, nil
-	}
-	return "", errors.Errorf("result isn't string: %v", result)
+	return r.NameContext(context.Background())
+}
+
+// NameContext is like Name but honors ctx cancellation and deadlines
+func (r *RTorrent) NameContext(ctx context.Context) (string, error) {
+	var name string
+	err := r.call(ctx, &name, "system.hostname")
+	return name, err
 }
 
 func (r *RTorrent) ListMethods() ([]string, error) {
-	results, err := r.xmlrpcClient.Call("system.listMethods")
-	if err != nil {
-		return []string{}, errors.Wrap(err, "system.listMethods XMLRPC call failed")
-	}
+	return r.ListMethodsContext(context.Background())
+}
 
-	methods := make([]string, 0)
-	for _, outerResult := range results.([]interface{}) {
-		for _, innerResult := range outerResult.([]interface{}) {
-			method := innerResult.(string)
-			methods = append(methods, method)
-		}
+// ListMethodsContext is like ListMethods but honors ctx cancellation and deadlines
+func (r *RTorrent) ListMethodsContext(ctx context.Context) ([]string, error) {
+	var methods []string
+	if err := r.call(ctx, &methods, "system.listMethods"); err != nil {
+		return []string{}, err
 	}
-
 	return methods, nil
-
 }
 
 func (r *RTorrent) MethodSignature(methodName string) (string, error) {
-	result, err := r.xmlrpcClient.Call("system.methodHelp", methodName)
+	return r.MethodSignatureContext(context.Background(), methodName)
+}
+
+// MethodSignatureContext is like MethodSignature but honors ctx cancellation and deadlines
+func (r *RTorrent) MethodSignatureContext(ctx context.Context, methodName string) (string, error) {
+	var signature string
+	err := r.call(ctx, &signature, "system.methodHelp", methodName)
+	return signature, err
+}
+
+// call calls `method` and decodes its single result into `out`
+func (r *RTorrent) call(ctx context.Context, out interface{}, method string, args ...interface{}) error {
+	value, err := r.transport.Call(ctx, method, args...)
 	if err != nil {
-		return "", errors.Wrap(err, "system.methodHelp XMLRPC call failed")
+		return wrapCallError(method, err)
 	}
+	return decodeError(method, xmlrpc.Decode(value, out))
+}
 
-	fmt.Printf("%+v\n", result)
+// callAll runs calls in a single round trip, failing with the first call
+// that failed
+func (r *RTorrent) callAll(ctx context.Context, calls ...Call) error {
+	results, err := r.transport.Multicall(ctx, calls)
+	if err != nil {
+		return wrapCallError("system.multicall", err)
+	}
+	for i, result := range results {
+		if result.Err != nil {
+			return wrapCallError(calls[i].Method, result.Err)
+		}
+	}
+	return nil
+}
 
-	if signatures, ok := result.([]interface{}); ok {
-		result = signatures[0]
+// multicall calls a *.multicall `method` fetching `fields` for every item
+// matched by `target`, and decodes the rows into the slice pointed to by `out`
+func (r *RTorrent) multicall(ctx context.Context, out interface{}, method string, target []interface{}, fields []string) error {
+	args := append([]interface{}{}, target...)
+	for _, field := range fields {
+		args = append(args, field)
 	}
-	if signature, ok := result.(string); ok {
-		return signature, nil
+	rows, err := r.transport.Call(ctx, method, args...)
+	if err != nil {
+		return wrapCallError(method, err)
 	}
-	return "", errors.Errorf("result isn't string: %v", result)
+	return decodeError(method, xmlrpc.DecodeMulticall(rows, fields, out))
 }
@@ -1,3 +1,6 @@
+//go:build integration
+// +build integration
+
 package rtorrent
 
 import (
@@ -61,7 +64,7 @@ func TestRTorrent(t *testing.T) {
 
 	t.Run("add", func(t *testing.T) {
 		t.Run("by url", func(t *testing.T) {
-			err := client.Add("http://releases.ubuntu.com/19.04/ubuntu-19.04-live-server-amd64.iso.torrent")
+			err := client.AddTorrentURL("http://releases.ubuntu.com/19.04/ubuntu-19.04-live-server-amd64.iso.torrent")
 			require.NoError(t, err)
 
 			t.Run("get torrent", func(t *testing.T) {
@@ -86,7 +89,8 @@ func TestRTorrent(t *testing.T) {
 				require.Equal(t, "B7B0FBAB74A85D4AC170662C645982A862826455", torrents[0].Hash)
 				require.Equal(t, "ubuntu-19.04-live-server-amd64.iso", torrents[0].Name)
 				require.Equal(t, "", torrents[0].Label)
-				require.Equal(t, 784334848, torrents[0].Size)
+				require.Empty(t, torrents[0].Labels())
+				require.Equal(t, int64(784334848), torrents[0].Size)
 				require.Equal(t, "/downloads/incoming/ubuntu-19.04-live-server-amd64.iso", torrents[0].Path)
 				require.False(t, torrents[0].Completed)
 
@@ -102,7 +106,7 @@ func TestRTorrent(t *testing.T) {
 				})
 
 				t.Run("single get", func(t *testing.T) {
-					torrent, err := client.GetTorrent(torrents[0].Hash)
+					torrent, err := client.GetTorrent(Torrent{Hash: torrents[0].Hash})
 					require.NoError(t, err)
 					require.NotEmpty(t, torrent.Hash)
 					require.NotEmpty(t, torrent.Name)
@@ -130,6 +134,7 @@ func TestRTorrent(t *testing.T) {
 						tries++
 					}
 					require.Equal(t, "TestLabel", torrents[0].Label)
+					require.Equal(t, []string{"TestLabel"}, torrents[0].Labels())
 				})
 
 				t.Run("get status", func(t *testing.T) {
@@ -220,7 +225,8 @@ func TestRTorrent(t *testing.T) {
 				require.Equal(t, "B7B0FBAB74A85D4AC170662C645982A862826455", torrents[0].Hash)
 				require.Equal(t, "ubuntu-19.04-live-server-amd64.iso", torrents[0].Name)
 				require.Equal(t, "", torrents[0].Label)
-				require.Equal(t, 784334848, torrents[0].Size)
+				require.Empty(t, torrents[0].Labels())
+				require.Equal(t, int64(784334848), torrents[0].Size)
 				require.Equal(t, "/downloads/incoming/ubuntu-19.04-live-server-amd64.iso", torrents[0].Path)
 				require.False(t, torrents[0].Completed)
 
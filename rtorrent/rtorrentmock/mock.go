@@ -0,0 +1,1256 @@
+// Package rtorrentmock provides an in-memory implementation of rtorrent.Client
+// for unit tests.
+//
+// A Client records every call made to it and answers from state scripted by
+// the test: the torrents of each view, the files, trackers and peers of each
+// torrent, the values reported for the instance itself and the errors methods
+// should fail with.
+//
+//	mock := rtorrentmock.New()
+//	mock.SetTorrents(rtorrent.ViewMain, rtorrent.Torrent{Hash: "ABC", Name: "foo"})
+//	mock.SetError("StartTorrent", errors.New("boom"))
+//	service := NewService(mock) // takes an rtorrent.Client
+//	...
+//	require.Len(t, mock.CallsTo("StartTorrent"), 1)
+package rtorrentmock
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/tab1293/go-rtorrent/metainfo"
+	"github.com/tab1293/go-rtorrent/rtorrent"
+)
+
+// Call is a recorded call. Method is the name of the rtorrent.Client method
+// without its Context suffix, Args are its arguments without the context.
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+// Global holds the values reported for the rTorrent instance itself
+type Global struct {
+	IP        string
+	Name      string
+	DownTotal int64
+	DownRate  int
+	UpTotal   int64
+	UpRate    int
+	Methods   []string
+
+	DownLimit        rtorrent.Rate
+	UpLimit          rtorrent.Rate
+	Throttles        map[string]Throttle
+	MaxPeers         int
+	MaxPeersSeed     int
+	MaxUploads       int
+	MaxUploadsGlobal int
+}
+
+// Throttle is a throttle group, by name in Global.Throttles
+type Throttle struct {
+	DownLimit rtorrent.Rate
+	UpLimit   rtorrent.Rate
+}
+
+// Client is an in-memory rtorrent.Client, safe for concurrent use
+type Client struct {
+	mu       sync.Mutex
+	calls    []Call
+	errs     map[string]error
+	views    map[rtorrent.View][]rtorrent.Torrent
+	files    map[string][]rtorrent.File
+	trackers map[string][]rtorrent.Tracker
+	peers    map[string][]rtorrent.Peer
+	global   Global
+}
+
+var _ rtorrent.Client = (*Client)(nil)
+
+// New returns an empty Client: no torrents, and no scripted errors
+func New() *Client {
+	return &Client{
+		errs:     map[string]error{},
+		views:    map[rtorrent.View][]rtorrent.Torrent{},
+		files:    map[string][]rtorrent.File{},
+		trackers: map[string][]rtorrent.Tracker{},
+		peers:    map[string][]rtorrent.Peer{},
+	}
+}
+
+// SetTorrents sets the torrents returned by GetTorrents for `view`.
+// GetTorrent finds torrents by hash in any view.
+func (c *Client) SetTorrents(view rtorrent.View, torrents ...rtorrent.Torrent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.views[view] = append([]rtorrent.Torrent(nil), torrents...)
+}
+
+// SetFiles sets the files returned by GetFiles for the torrent with `hash`
+func (c *Client) SetFiles(hash string, files ...rtorrent.File) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files[hash] = append([]rtorrent.File(nil), files...)
+}
+
+// SetTrackers sets the trackers returned by GetTrackers for the torrent with `hash`
+func (c *Client) SetTrackers(hash string, trackers ...rtorrent.Tracker) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.trackers[hash] = append([]rtorrent.Tracker(nil), trackers...)
+}
+
+// SetPeers sets the peers returned by GetPeers for the torrent with `hash`
+func (c *Client) SetPeers(hash string, peers ...rtorrent.Peer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.peers[hash] = append([]rtorrent.Peer(nil), peers...)
+}
+
+// SetGlobal sets the values reported for the instance itself
+func (c *Client) SetGlobal(g Global) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.global = g
+}
+
+// SetError makes every following call to `method` (e.g. "GetTorrents",
+// with or without the Context suffix) fail with err; a nil err clears it
+func (c *Client) SetError(method string, err error) {
+	method = strings.TrimSuffix(method, "Context")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil {
+		delete(c.errs, method)
+		return
+	}
+	c.errs[method] = err
+}
+
+// Calls returns all the calls made so far, in order
+func (c *Client) Calls() []Call {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Call(nil), c.calls...)
+}
+
+// CallsTo returns the calls made so far to `method`, in order
+func (c *Client) CallsTo(method string) []Call {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var calls []Call
+	for _, call := range c.calls {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Reset forgets the recorded calls
+func (c *Client) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = nil
+}
+
+// record records a call to `method`, then returns the error it should fail
+// with: the context's, or the scripted one. c.mu must be held.
+func (c *Client) record(ctx context.Context, method string, args ...interface{}) error {
+	c.calls = append(c.calls, Call{Method: method, Args: args})
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.errs[method]
+}
+
+// find returns the torrent with `hash` from any view. c.mu must be held.
+func (c *Client) find(hash string) (rtorrent.Torrent, bool) {
+	for _, torrents := range c.views {
+		for _, torrent := range torrents {
+			if torrent.Hash == hash {
+				return torrent, true
+			}
+		}
+	}
+	return rtorrent.Torrent{}, false
+}
+
+func stringArgs(s []string) []interface{} {
+	args := make([]interface{}, len(s))
+	for i := range s {
+		args[i] = s[i]
+	}
+	return args
+}
+
+// update applies fn to every copy of the torrent with `hash`. c.mu must be held.
+func (c *Client) update(hash string, fn func(t *rtorrent.Torrent)) {
+	for _, torrents := range c.views {
+		for i := range torrents {
+			if torrents[i].Hash == hash {
+				fn(&torrents[i])
+			}
+		}
+	}
+}
+
+// setPriority sets the priority of file i of the torrent with `hash`. c.mu must be held.
+func (c *Client) setPriority(hash string, i int, p int) error {
+	files := c.files[hash]
+	if i < 0 || i >= len(files) {
+		return errors.Errorf("no file %d in torrent %s", i, hash)
+	}
+	files[i].Priority = p
+	return nil
+}
+
+func (c *Client) Shutdown() error {
+	return c.ShutdownContext(context.Background())
+}
+
+func (c *Client) ShutdownContext(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.record(ctx, "Shutdown")
+}
+
+func (c *Client) SetSessionDirectory(d string) error {
+	return c.SetSessionDirectoryContext(context.Background(), d)
+}
+
+func (c *Client) SetSessionDirectoryContext(ctx context.Context, d string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.record(ctx, "SetSessionDirectory", d)
+}
+
+func (c *Client) SetDefaultDirectory(t rtorrent.Torrent, d string) error {
+	return c.SetDefaultDirectoryContext(context.Background(), t, d)
+}
+
+func (c *Client) SetDefaultDirectoryContext(ctx context.Context, t rtorrent.Torrent, d string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.record(ctx, "SetDefaultDirectory", t, d)
+}
+
+func (c *Client) GetTorrent(t rtorrent.Torrent) (rtorrent.Torrent, error) {
+	return c.GetTorrentContext(context.Background(), t)
+}
+
+func (c *Client) GetTorrentContext(ctx context.Context, t rtorrent.Torrent) (rtorrent.Torrent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "GetTorrent", t); err != nil {
+		return rtorrent.Torrent{}, err
+	}
+	if torrent, ok := c.find(t.Hash); ok {
+		return torrent, nil
+	}
+	return rtorrent.Torrent{}, rtorrent.ErrTorrentNotFound
+}
+
+func (c *Client) GetStatus(t rtorrent.Torrent) (rtorrent.Status, error) {
+	return c.GetStatusContext(context.Background(), t)
+}
+
+// GetStatusContext returns the Status derived from the scripted torrent
+func (c *Client) GetStatusContext(ctx context.Context, t rtorrent.Torrent) (rtorrent.Status, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "GetStatus", t); err != nil {
+		return rtorrent.Status{}, err
+	}
+	torrent, ok := c.find(t.Hash)
+	if !ok {
+		return rtorrent.Status{}, rtorrent.ErrTorrentNotFound
+	}
+	return torrent.Status(), nil
+}
+
+func (c *Client) GetTorrentsByHash(hashes ...string) ([]rtorrent.Torrent, error) {
+	return c.GetTorrentsByHashContext(context.Background(), hashes...)
+}
+
+func (c *Client) GetTorrentsByHashContext(ctx context.Context, hashes ...string) ([]rtorrent.Torrent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "GetTorrentsByHash", stringArgs(hashes)...); err != nil {
+		return nil, err
+	}
+	torrents := []rtorrent.Torrent{}
+	for _, hash := range hashes {
+		if torrent, ok := c.find(hash); ok {
+			torrents = append(torrents, torrent)
+		}
+	}
+	return torrents, nil
+}
+
+// Torrents returns a query recorded as a "Torrents" call with the view, the
+// hash filter and the selected fields as args. It returns the torrents of
+// the view, or the ones with the filtered hashes from any view, unchanged.
+func (c *Client) Torrents(view rtorrent.View) *rtorrent.TorrentQuery {
+	return rtorrent.NewTorrentQuery(view, c.runTorrentQuery)
+}
+
+func (c *Client) runTorrentQuery(ctx context.Context, q *rtorrent.TorrentQuery) ([]rtorrent.Torrent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "Torrents", q.View(), q.HashFilter(), q.SelectedFields()); err != nil {
+		return nil, err
+	}
+	if q.HashFilter() == nil {
+		return append([]rtorrent.Torrent{}, c.views[q.View()]...), nil
+	}
+	torrents := []rtorrent.Torrent{}
+	for _, hash := range q.HashFilter() {
+		if torrent, ok := c.find(hash); ok {
+			torrents = append(torrents, torrent)
+		}
+	}
+	return torrents, nil
+}
+
+func (c *Client) GetTorrents(view rtorrent.View) ([]rtorrent.Torrent, error) {
+	return c.GetTorrentsContext(context.Background(), view)
+}
+
+func (c *Client) GetTorrentsContext(ctx context.Context, view rtorrent.View) ([]rtorrent.Torrent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "GetTorrents", view); err != nil {
+		return nil, err
+	}
+	return append([]rtorrent.Torrent{}, c.views[view]...), nil
+}
+
+func (c *Client) AddTorrentURL(url string) error {
+	return c.AddTorrentURLContext(context.Background(), url)
+}
+
+func (c *Client) AddTorrentURLContext(ctx context.Context, url string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.record(ctx, "AddTorrentURL", url)
+}
+
+func (c *Client) AddTorrent(data []byte) error {
+	return c.AddTorrentContext(context.Background(), data)
+}
+
+func (c *Client) AddTorrentContext(ctx context.Context, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.record(ctx, "AddTorrent", data)
+}
+
+func (c *Client) AddTorrentURLWithOptions(url string, opts rtorrent.AddOptions) (string, error) {
+	return c.AddTorrentURLWithOptionsContext(context.Background(), url, opts)
+}
+
+// AddTorrentURLWithOptionsContext returns the info-hash of magnet links, like
+// the real client
+func (c *Client) AddTorrentURLWithOptionsContext(ctx context.Context, url string, opts rtorrent.AddOptions) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "AddTorrentURLWithOptions", url, opts); err != nil {
+		return "", err
+	}
+	return rtorrent.MagnetHash(url), nil
+}
+
+func (c *Client) AddTorrentWithOptions(data []byte, opts rtorrent.AddOptions) (string, error) {
+	return c.AddTorrentWithOptionsContext(context.Background(), data, opts)
+}
+
+// AddTorrentWithOptionsContext returns the info-hash of `data`, or fails if it
+// isn't valid metainfo, like the real client
+func (c *Client) AddTorrentWithOptionsContext(ctx context.Context, data []byte, opts rtorrent.AddOptions) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "AddTorrentWithOptions", data, opts); err != nil {
+		return "", err
+	}
+	return rtorrent.InfoHash(data)
+}
+
+func (c *Client) AddMetaInfo(meta *metainfo.MetaInfo, opts rtorrent.AddOptions) (string, error) {
+	return c.AddMetaInfoContext(context.Background(), meta, opts)
+}
+
+func (c *Client) AddMetaInfoContext(ctx context.Context, meta *metainfo.MetaInfo, opts rtorrent.AddOptions) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "AddMetaInfo", meta, opts); err != nil {
+		return "", err
+	}
+	return meta.InfoHash(), nil
+}
+
+func (c *Client) StartTorrent(t rtorrent.Torrent) error {
+	return c.StartTorrentContext(context.Background(), t)
+}
+
+func (c *Client) StartTorrentContext(ctx context.Context, t rtorrent.Torrent) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "StartTorrent", t); err != nil {
+		return err
+	}
+	c.update(t.Hash, func(t *rtorrent.Torrent) { t.State = 1 })
+	return nil
+}
+
+func (c *Client) StopTorrent(t rtorrent.Torrent) error {
+	return c.StopTorrentContext(context.Background(), t)
+}
+
+func (c *Client) StopTorrentContext(ctx context.Context, t rtorrent.Torrent) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "StopTorrent", t); err != nil {
+		return err
+	}
+	c.update(t.Hash, func(t *rtorrent.Torrent) { t.State = 0 })
+	return nil
+}
+
+func (c *Client) CloseTorrent(t rtorrent.Torrent) error {
+	return c.CloseTorrentContext(context.Background(), t)
+}
+
+func (c *Client) CloseTorrentContext(ctx context.Context, t rtorrent.Torrent) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "CloseTorrent", t); err != nil {
+		return err
+	}
+	c.update(t.Hash, func(t *rtorrent.Torrent) { t.State = 0 })
+	return nil
+}
+
+func (c *Client) Delete(t rtorrent.Torrent) error {
+	return c.DeleteContext(context.Background(), t)
+}
+
+// DeleteContext removes the torrent from every view, and forgets its files,
+// trackers and peers
+func (c *Client) DeleteContext(ctx context.Context, t rtorrent.Torrent) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "Delete", t); err != nil {
+		return err
+	}
+	for view, torrents := range c.views {
+		kept := torrents[:0]
+		for _, torrent := range torrents {
+			if torrent.Hash != t.Hash {
+				kept = append(kept, torrent)
+			}
+		}
+		c.views[view] = kept
+	}
+	delete(c.files, t.Hash)
+	delete(c.trackers, t.Hash)
+	delete(c.peers, t.Hash)
+	return nil
+}
+
+func (c *Client) GetLabel(t rtorrent.Torrent) (string, error) {
+	return c.GetLabelContext(context.Background(), t)
+}
+
+func (c *Client) GetLabelContext(ctx context.Context, t rtorrent.Torrent) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "GetLabel", t); err != nil {
+		return "", err
+	}
+	torrent, ok := c.find(t.Hash)
+	if !ok {
+		return "", rtorrent.ErrTorrentNotFound
+	}
+	return torrent.Label, nil
+}
+
+func (c *Client) SetLabel(t rtorrent.Torrent, label string) error {
+	return c.SetLabelContext(context.Background(), t, label)
+}
+
+func (c *Client) SetLabelContext(ctx context.Context, t rtorrent.Torrent, label string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "SetLabel", t, label); err != nil {
+		return err
+	}
+	c.setLabels(t.Hash, label)
+	return nil
+}
+
+func (c *Client) SetLabels(t rtorrent.Torrent, labels ...string) error {
+	return c.SetLabelsContext(context.Background(), t, labels...)
+}
+
+func (c *Client) SetLabelsContext(ctx context.Context, t rtorrent.Torrent, labels ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "SetLabels", append([]interface{}{t}, stringArgs(labels)...)...); err != nil {
+		return err
+	}
+	c.setLabels(t.Hash, labels...)
+	return nil
+}
+
+// setLabels stores labels in Custom1 as rTorrent would. c.mu must be held.
+func (c *Client) setLabels(hash string, labels ...string) {
+	c.update(hash, func(t *rtorrent.Torrent) {
+		t.Custom1 = rtorrent.EncodeLabels(labels...)
+		t.Label = ""
+		if labels := t.Labels(); len(labels) > 0 {
+			t.Label = labels[0]
+		}
+	})
+}
+
+func (c *Client) ListLabels() ([]string, error) {
+	return c.ListLabelsContext(context.Background())
+}
+
+// ListLabelsContext returns the labels of the torrents of rtorrent.ViewMain,
+// as stored in Custom1
+func (c *Client) ListLabelsContext(ctx context.Context) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "ListLabels"); err != nil {
+		return nil, err
+	}
+	labels := []string{}
+	seen := make(map[string]bool)
+	for _, torrent := range c.views[rtorrent.ViewMain] {
+		for _, label := range torrent.Labels() {
+			if !seen[label] {
+				seen[label] = true
+				labels = append(labels, label)
+			}
+		}
+	}
+	sort.Strings(labels)
+	return labels, nil
+}
+
+func (c *Client) GetTorrentsByLabel(view rtorrent.View, label string) ([]rtorrent.Torrent, error) {
+	return c.GetTorrentsByLabelContext(context.Background(), view, label)
+}
+
+func (c *Client) GetTorrentsByLabelContext(ctx context.Context, view rtorrent.View, label string) ([]rtorrent.Torrent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "GetTorrentsByLabel", view, label); err != nil {
+		return nil, err
+	}
+	torrents := []rtorrent.Torrent{}
+	for _, torrent := range c.views[view] {
+		labels := torrent.Labels()
+		if label == "" && len(labels) == 0 {
+			torrents = append(torrents, torrent)
+			continue
+		}
+		for _, l := range labels {
+			if l == label {
+				torrents = append(torrents, torrent)
+				break
+			}
+		}
+	}
+	return torrents, nil
+}
+
+func (c *Client) ListViews() ([]rtorrent.View, error) {
+	return c.ListViewsContext(context.Background())
+}
+
+// ListViewsContext returns the views torrents were scripted or created in,
+// sorted
+func (c *Client) ListViewsContext(ctx context.Context) ([]rtorrent.View, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "ListViews"); err != nil {
+		return nil, err
+	}
+	views := make([]rtorrent.View, 0, len(c.views))
+	for view := range c.views {
+		views = append(views, view)
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i] < views[j] })
+	return views, nil
+}
+
+func (c *Client) CreateView(name rtorrent.View, filter string) error {
+	return c.CreateViewContext(context.Background(), name, filter)
+}
+
+// CreateViewContext creates an empty view, whatever the filter
+func (c *Client) CreateViewContext(ctx context.Context, name rtorrent.View, filter string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "CreateView", name, filter); err != nil {
+		return err
+	}
+	if _, ok := c.views[name]; ok {
+		return errors.Wrapf(rtorrent.ErrInvalidArgument, "view %s already exists", name)
+	}
+	c.views[name] = []rtorrent.Torrent{}
+	return nil
+}
+
+func (c *Client) ClearView(name rtorrent.View) error {
+	return c.ClearViewContext(context.Background(), name)
+}
+
+// ClearViewContext empties the view, which is kept like rTorrent does
+func (c *Client) ClearViewContext(ctx context.Context, name rtorrent.View) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "ClearView", name); err != nil {
+		return err
+	}
+	if _, ok := c.views[name]; !ok {
+		return errors.Wrapf(rtorrent.ErrInvalidArgument, "view %s doesn't exist", name)
+	}
+	c.views[name] = []rtorrent.Torrent{}
+	return nil
+}
+
+func (c *Client) AddToView(t rtorrent.Torrent, name rtorrent.View) error {
+	return c.AddToViewContext(context.Background(), t, name)
+}
+
+// AddToViewContext copies the torrent, as found in any view, to the view
+func (c *Client) AddToViewContext(ctx context.Context, t rtorrent.Torrent, name rtorrent.View) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "AddToView", t, name); err != nil {
+		return err
+	}
+	torrent, ok := c.find(t.Hash)
+	if !ok {
+		return rtorrent.ErrTorrentNotFound
+	}
+	for _, other := range c.views[name] {
+		if other.Hash == t.Hash {
+			return nil
+		}
+	}
+	c.views[name] = append(c.views[name], torrent)
+	return nil
+}
+
+func (c *Client) RemoveFromView(t rtorrent.Torrent, name rtorrent.View) error {
+	return c.RemoveFromViewContext(context.Background(), t, name)
+}
+
+func (c *Client) RemoveFromViewContext(ctx context.Context, t rtorrent.Torrent, name rtorrent.View) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "RemoveFromView", t, name); err != nil {
+		return err
+	}
+	torrents := c.views[name]
+	for i := range torrents {
+		if torrents[i].Hash == t.Hash {
+			c.views[name] = append(torrents[:i:i], torrents[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (c *Client) GetTrackers(t rtorrent.Torrent) ([]rtorrent.Tracker, error) {
+	return c.GetTrackersContext(context.Background(), t)
+}
+
+func (c *Client) GetTrackersContext(ctx context.Context, t rtorrent.Torrent) ([]rtorrent.Tracker, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "GetTrackers", t); err != nil {
+		return nil, err
+	}
+	trackers := append([]rtorrent.Tracker{}, c.trackers[t.Hash]...)
+	for i := range trackers {
+		trackers[i].Index = i
+	}
+	return trackers, nil
+}
+
+func (c *Client) AddTracker(t rtorrent.Torrent, url string) error {
+	return c.AddTrackerContext(context.Background(), t, url)
+}
+
+// AddTrackerContext appends an enabled tracker to the scripted ones
+func (c *Client) AddTrackerContext(ctx context.Context, t rtorrent.Torrent, url string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "AddTracker", t, url); err != nil {
+		return err
+	}
+	c.trackers[t.Hash] = append(c.trackers[t.Hash], rtorrent.Tracker{URL: url, Enabled: true})
+	return nil
+}
+
+func (c *Client) EnableTracker(t rtorrent.Torrent, i int) error {
+	return c.EnableTrackerContext(context.Background(), t, i)
+}
+
+func (c *Client) EnableTrackerContext(ctx context.Context, t rtorrent.Torrent, i int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "EnableTracker", t, i); err != nil {
+		return err
+	}
+	return c.setTrackerEnabled(t.Hash, i, true)
+}
+
+func (c *Client) DisableTracker(t rtorrent.Torrent, i int) error {
+	return c.DisableTrackerContext(context.Background(), t, i)
+}
+
+func (c *Client) DisableTrackerContext(ctx context.Context, t rtorrent.Torrent, i int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "DisableTracker", t, i); err != nil {
+		return err
+	}
+	return c.setTrackerEnabled(t.Hash, i, false)
+}
+
+// setTrackerEnabled enables or disables tracker i of the torrent with `hash`. c.mu must be held.
+func (c *Client) setTrackerEnabled(hash string, i int, enabled bool) error {
+	trackers := c.trackers[hash]
+	if i < 0 || i >= len(trackers) {
+		return errors.Errorf("no tracker %d in torrent %s", i, hash)
+	}
+	trackers[i].Enabled = enabled
+	return nil
+}
+
+func (c *Client) AnnounceNow(t rtorrent.Torrent) error {
+	return c.AnnounceNowContext(context.Background(), t)
+}
+
+func (c *Client) AnnounceNowContext(ctx context.Context, t rtorrent.Torrent) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.record(ctx, "AnnounceNow", t)
+}
+
+func (c *Client) GetPeers(t rtorrent.Torrent) ([]rtorrent.Peer, error) {
+	return c.GetPeersContext(context.Background(), t)
+}
+
+func (c *Client) GetPeersContext(ctx context.Context, t rtorrent.Torrent) ([]rtorrent.Peer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "GetPeers", t); err != nil {
+		return nil, err
+	}
+	return append([]rtorrent.Peer{}, c.peers[t.Hash]...), nil
+}
+
+func (c *Client) DisconnectPeer(t rtorrent.Torrent, p rtorrent.Peer) error {
+	return c.DisconnectPeerContext(context.Background(), t, p)
+}
+
+// DisconnectPeerContext removes the peer from the scripted ones
+func (c *Client) DisconnectPeerContext(ctx context.Context, t rtorrent.Torrent, p rtorrent.Peer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "DisconnectPeer", t, p); err != nil {
+		return err
+	}
+	return c.disconnect(t.Hash, p.ID)
+}
+
+func (c *Client) BanPeer(t rtorrent.Torrent, p rtorrent.Peer) error {
+	return c.BanPeerContext(context.Background(), t, p)
+}
+
+// BanPeerContext removes the peer from the scripted ones
+func (c *Client) BanPeerContext(ctx context.Context, t rtorrent.Torrent, p rtorrent.Peer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "BanPeer", t, p); err != nil {
+		return err
+	}
+	return c.disconnect(t.Hash, p.ID)
+}
+
+// disconnect removes the peer `id` of the torrent with `hash`. c.mu must be held.
+func (c *Client) disconnect(hash string, id string) error {
+	peers := c.peers[hash]
+	for i := range peers {
+		if peers[i].ID == id {
+			c.peers[hash] = append(peers[:i:i], peers[i+1:]...)
+			return nil
+		}
+	}
+	return errors.Errorf("no peer %s in torrent %s", id, hash)
+}
+
+func (c *Client) SnubPeer(t rtorrent.Torrent, p rtorrent.Peer) error {
+	return c.SnubPeerContext(context.Background(), t, p)
+}
+
+func (c *Client) SnubPeerContext(ctx context.Context, t rtorrent.Torrent, p rtorrent.Peer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "SnubPeer", t, p); err != nil {
+		return err
+	}
+	return c.setSnubbed(t.Hash, p.ID, true)
+}
+
+func (c *Client) UnsnubPeer(t rtorrent.Torrent, p rtorrent.Peer) error {
+	return c.UnsnubPeerContext(context.Background(), t, p)
+}
+
+func (c *Client) UnsnubPeerContext(ctx context.Context, t rtorrent.Torrent, p rtorrent.Peer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "UnsnubPeer", t, p); err != nil {
+		return err
+	}
+	return c.setSnubbed(t.Hash, p.ID, false)
+}
+
+// setSnubbed snubs or unsnubs the peer `id` of the torrent with `hash`. c.mu must be held.
+func (c *Client) setSnubbed(hash string, id string, snubbed bool) error {
+	peers := c.peers[hash]
+	for i := range peers {
+		if peers[i].ID == id {
+			peers[i].IsSnubbed = snubbed
+			return nil
+		}
+	}
+	return errors.Errorf("no peer %s in torrent %s", id, hash)
+}
+
+func (c *Client) GetFiles(t rtorrent.Torrent) ([]rtorrent.File, error) {
+	return c.GetFilesContext(context.Background(), t)
+}
+
+func (c *Client) GetFilesContext(ctx context.Context, t rtorrent.Torrent) ([]rtorrent.File, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "GetFiles", t); err != nil {
+		return nil, err
+	}
+	files := append([]rtorrent.File{}, c.files[t.Hash]...)
+	for i := range files {
+		files[i].Index = i
+	}
+	return files, nil
+}
+
+func (c *Client) SetFilePrority(t rtorrent.Torrent, i int, p int) error {
+	return c.SetFileProrityContext(context.Background(), t, i, p)
+}
+
+func (c *Client) SetFileProrityContext(ctx context.Context, t rtorrent.Torrent, i int, p int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "SetFilePrority", t, i, p); err != nil {
+		return err
+	}
+	return c.setPriority(t.Hash, i, p)
+}
+
+func (c *Client) SetFilePriorities(t rtorrent.Torrent, priorities map[int]int) error {
+	return c.SetFilePrioritiesContext(context.Background(), t, priorities)
+}
+
+func (c *Client) SetFilePrioritiesContext(ctx context.Context, t rtorrent.Torrent, priorities map[int]int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "SetFilePriorities", t, priorities); err != nil {
+		return err
+	}
+	for i, p := range priorities {
+		if err := c.setPriority(t.Hash, i, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) SetAllFilePriorities(t rtorrent.Torrent, p int) error {
+	return c.SetAllFilePrioritiesContext(context.Background(), t, p)
+}
+
+func (c *Client) SetAllFilePrioritiesContext(ctx context.Context, t rtorrent.Torrent, p int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "SetAllFilePriorities", t, p); err != nil {
+		return err
+	}
+	for i := range c.files[t.Hash] {
+		c.files[t.Hash][i].Priority = p
+	}
+	return nil
+}
+
+func (c *Client) DownTotal() (int64, error) {
+	return c.DownTotalContext(context.Background())
+}
+
+func (c *Client) DownTotalContext(ctx context.Context) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "DownTotal"); err != nil {
+		return 0, err
+	}
+	return c.global.DownTotal, nil
+}
+
+func (c *Client) DownRate() (int, error) {
+	return c.DownRateContext(context.Background())
+}
+
+func (c *Client) DownRateContext(ctx context.Context) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "DownRate"); err != nil {
+		return 0, err
+	}
+	return c.global.DownRate, nil
+}
+
+func (c *Client) UpTotal() (int64, error) {
+	return c.UpTotalContext(context.Background())
+}
+
+func (c *Client) UpTotalContext(ctx context.Context) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "UpTotal"); err != nil {
+		return 0, err
+	}
+	return c.global.UpTotal, nil
+}
+
+func (c *Client) UpRate() (int, error) {
+	return c.UpRateContext(context.Background())
+}
+
+func (c *Client) UpRateContext(ctx context.Context) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "UpRate"); err != nil {
+		return 0, err
+	}
+	return c.global.UpRate, nil
+}
+
+func (c *Client) IP() (string, error) {
+	return c.IPContext(context.Background())
+}
+
+func (c *Client) IPContext(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "IP"); err != nil {
+		return "", err
+	}
+	return c.global.IP, nil
+}
+
+func (c *Client) Name() (string, error) {
+	return c.NameContext(context.Background())
+}
+
+func (c *Client) NameContext(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "Name"); err != nil {
+		return "", err
+	}
+	return c.global.Name, nil
+}
+
+func (c *Client) DownLimit() (rtorrent.Rate, error) {
+	return c.DownLimitContext(context.Background())
+}
+
+func (c *Client) DownLimitContext(ctx context.Context) (rtorrent.Rate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "DownLimit"); err != nil {
+		return 0, err
+	}
+	return c.global.DownLimit, nil
+}
+
+func (c *Client) SetDownLimit(rate rtorrent.Rate) error {
+	return c.SetDownLimitContext(context.Background(), rate)
+}
+
+func (c *Client) SetDownLimitContext(ctx context.Context, rate rtorrent.Rate) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "SetDownLimit", rate); err != nil {
+		return err
+	}
+	c.global.DownLimit = rate
+	return nil
+}
+
+func (c *Client) UpLimit() (rtorrent.Rate, error) {
+	return c.UpLimitContext(context.Background())
+}
+
+func (c *Client) UpLimitContext(ctx context.Context) (rtorrent.Rate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "UpLimit"); err != nil {
+		return 0, err
+	}
+	return c.global.UpLimit, nil
+}
+
+func (c *Client) SetUpLimit(rate rtorrent.Rate) error {
+	return c.SetUpLimitContext(context.Background(), rate)
+}
+
+func (c *Client) SetUpLimitContext(ctx context.Context, rate rtorrent.Rate) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "SetUpLimit", rate); err != nil {
+		return err
+	}
+	c.global.UpLimit = rate
+	return nil
+}
+
+func (c *Client) ThrottleDownLimit(name string) (rtorrent.Rate, error) {
+	return c.ThrottleDownLimitContext(context.Background(), name)
+}
+
+func (c *Client) ThrottleDownLimitContext(ctx context.Context, name string) (rtorrent.Rate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "ThrottleDownLimit", name); err != nil {
+		return 0, err
+	}
+	t, ok := c.global.Throttles[name]
+	if !ok {
+		return 0, errors.Wrapf(rtorrent.ErrInvalidArgument, "throttle group %q isn't defined", name)
+	}
+	return t.DownLimit, nil
+}
+
+func (c *Client) SetThrottleDownLimit(name string, rate rtorrent.Rate) error {
+	return c.SetThrottleDownLimitContext(context.Background(), name, rate)
+}
+
+// SetThrottleDownLimitContext defines or changes the throttle in Global.Throttles
+func (c *Client) SetThrottleDownLimitContext(ctx context.Context, name string, rate rtorrent.Rate) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "SetThrottleDownLimit", name, rate); err != nil {
+		return err
+	}
+	if c.global.Throttles == nil {
+		c.global.Throttles = map[string]Throttle{}
+	}
+	t := c.global.Throttles[name]
+	t.DownLimit = rate
+	c.global.Throttles[name] = t
+	return nil
+}
+
+func (c *Client) ThrottleUpLimit(name string) (rtorrent.Rate, error) {
+	return c.ThrottleUpLimitContext(context.Background(), name)
+}
+
+func (c *Client) ThrottleUpLimitContext(ctx context.Context, name string) (rtorrent.Rate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "ThrottleUpLimit", name); err != nil {
+		return 0, err
+	}
+	t, ok := c.global.Throttles[name]
+	if !ok {
+		return 0, errors.Wrapf(rtorrent.ErrInvalidArgument, "throttle group %q isn't defined", name)
+	}
+	return t.UpLimit, nil
+}
+
+func (c *Client) SetThrottleUpLimit(name string, rate rtorrent.Rate) error {
+	return c.SetThrottleUpLimitContext(context.Background(), name, rate)
+}
+
+// SetThrottleUpLimitContext defines or changes the throttle in Global.Throttles
+func (c *Client) SetThrottleUpLimitContext(ctx context.Context, name string, rate rtorrent.Rate) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "SetThrottleUpLimit", name, rate); err != nil {
+		return err
+	}
+	if c.global.Throttles == nil {
+		c.global.Throttles = map[string]Throttle{}
+	}
+	t := c.global.Throttles[name]
+	t.UpLimit = rate
+	c.global.Throttles[name] = t
+	return nil
+}
+
+func (c *Client) SetThrottle(t rtorrent.Torrent, name string) error {
+	return c.SetThrottleContext(context.Background(), t, name)
+}
+
+func (c *Client) SetThrottleContext(ctx context.Context, t rtorrent.Torrent, name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "SetThrottle", t, name); err != nil {
+		return err
+	}
+	c.update(t.Hash, func(t *rtorrent.Torrent) { t.ThrottleName = name })
+	return nil
+}
+
+func (c *Client) MaxPeers() (int, error) {
+	return c.MaxPeersContext(context.Background())
+}
+
+func (c *Client) MaxPeersContext(ctx context.Context) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "MaxPeers"); err != nil {
+		return 0, err
+	}
+	return c.global.MaxPeers, nil
+}
+
+func (c *Client) SetMaxPeers(n int) error {
+	return c.SetMaxPeersContext(context.Background(), n)
+}
+
+func (c *Client) SetMaxPeersContext(ctx context.Context, n int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "SetMaxPeers", n); err != nil {
+		return err
+	}
+	c.global.MaxPeers = n
+	return nil
+}
+
+func (c *Client) MaxPeersSeed() (int, error) {
+	return c.MaxPeersSeedContext(context.Background())
+}
+
+func (c *Client) MaxPeersSeedContext(ctx context.Context) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "MaxPeersSeed"); err != nil {
+		return 0, err
+	}
+	return c.global.MaxPeersSeed, nil
+}
+
+func (c *Client) SetMaxPeersSeed(n int) error {
+	return c.SetMaxPeersSeedContext(context.Background(), n)
+}
+
+func (c *Client) SetMaxPeersSeedContext(ctx context.Context, n int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "SetMaxPeersSeed", n); err != nil {
+		return err
+	}
+	c.global.MaxPeersSeed = n
+	return nil
+}
+
+func (c *Client) MaxUploads() (int, error) {
+	return c.MaxUploadsContext(context.Background())
+}
+
+func (c *Client) MaxUploadsContext(ctx context.Context) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "MaxUploads"); err != nil {
+		return 0, err
+	}
+	return c.global.MaxUploads, nil
+}
+
+func (c *Client) SetMaxUploads(n int) error {
+	return c.SetMaxUploadsContext(context.Background(), n)
+}
+
+func (c *Client) SetMaxUploadsContext(ctx context.Context, n int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "SetMaxUploads", n); err != nil {
+		return err
+	}
+	c.global.MaxUploads = n
+	return nil
+}
+
+func (c *Client) MaxUploadsGlobal() (int, error) {
+	return c.MaxUploadsGlobalContext(context.Background())
+}
+
+func (c *Client) MaxUploadsGlobalContext(ctx context.Context) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "MaxUploadsGlobal"); err != nil {
+		return 0, err
+	}
+	return c.global.MaxUploadsGlobal, nil
+}
+
+func (c *Client) SetMaxUploadsGlobal(n int) error {
+	return c.SetMaxUploadsGlobalContext(context.Background(), n)
+}
+
+func (c *Client) SetMaxUploadsGlobalContext(ctx context.Context, n int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "SetMaxUploadsGlobal", n); err != nil {
+		return err
+	}
+	c.global.MaxUploadsGlobal = n
+	return nil
+}
+
+func (c *Client) ListMethods() ([]string, error) {
+	return c.ListMethodsContext(context.Background())
+}
+
+func (c *Client) ListMethodsContext(ctx context.Context) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(ctx, "ListMethods"); err != nil {
+		return []string{}, err
+	}
+	return append([]string{}, c.global.Methods...), nil
+}
+
+func (c *Client) MethodSignature(methodName string) (string, error) {
+	return c.MethodSignatureContext(context.Background(), methodName)
+}
+
+func (c *Client) MethodSignatureContext(ctx context.Context, methodName string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return "", c.record(ctx, "MethodSignature", methodName)
+}
@@ -0,0 +1,130 @@
+package rtorrentmock
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/tab1293/go-rtorrent/metainfo"
+	"github.com/tab1293/go-rtorrent/rtorrent"
+)
+
+func TestClient(t *testing.T) {
+	mock := New()
+	var client rtorrent.Client = mock
+
+	torrent := rtorrent.Torrent{Hash: "ABC", Name: "foo"}
+	mock.SetTorrents(rtorrent.ViewMain, torrent)
+	mock.SetFiles("ABC", rtorrent.File{Path: "a"}, rtorrent.File{Path: "b"})
+	mock.SetGlobal(Global{Name: "mock"})
+
+	torrents, err := client.GetTorrents(rtorrent.ViewMain)
+	require.NoError(t, err)
+	require.Equal(t, []rtorrent.Torrent{torrent}, torrents)
+	torrents, err = client.GetTorrents(rtorrent.ViewSeeding)
+	require.NoError(t, err)
+	require.Empty(t, torrents)
+
+	name, err := client.Name()
+	require.NoError(t, err)
+	require.Equal(t, "mock", name)
+
+	torrents, err = client.GetTorrentsByHash("XYZ", "ABC")
+	require.NoError(t, err)
+	require.Equal(t, []rtorrent.Torrent{torrent}, torrents)
+
+	require.NoError(t, client.StartTorrent(torrent))
+	got, err := client.GetTorrent(rtorrent.Torrent{Hash: "ABC"})
+	require.NoError(t, err)
+	require.Equal(t, 1, got.State)
+
+	require.NoError(t, client.SetLabels(torrent, "b", "a"))
+	labels, err := client.ListLabels()
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, labels)
+	torrents, err = client.GetTorrentsByLabel(rtorrent.ViewMain, "a")
+	require.NoError(t, err)
+	require.Len(t, torrents, 1)
+	torrents, err = client.Torrents(rtorrent.ViewMain).Label("c").Do()
+	require.NoError(t, err)
+	require.Empty(t, torrents)
+
+	mock.SetTrackers("ABC", rtorrent.Tracker{URL: "http://tracker/announce", Enabled: true})
+	require.NoError(t, client.AddTracker(torrent, "udp://tracker:1337"))
+	require.NoError(t, client.DisableTracker(torrent, 0))
+	trackers, err := client.GetTrackers(torrent)
+	require.NoError(t, err)
+	require.Equal(t, []rtorrent.Tracker{
+		{URL: "http://tracker/announce"},
+		{URL: "udp://tracker:1337", Enabled: true, Index: 1},
+	}, trackers)
+	require.Error(t, client.EnableTracker(torrent, 2))
+
+	mock.SetPeers("ABC", rtorrent.Peer{ID: "A"}, rtorrent.Peer{ID: "B"})
+	require.NoError(t, client.SnubPeer(torrent, rtorrent.Peer{ID: "B"}))
+	require.NoError(t, client.BanPeer(torrent, rtorrent.Peer{ID: "A"}))
+	require.Error(t, client.DisconnectPeer(torrent, rtorrent.Peer{ID: "A"}))
+	peers, err := client.GetPeers(torrent)
+	require.NoError(t, err)
+	require.Equal(t, []rtorrent.Peer{{ID: "B", IsSnubbed: true}}, peers)
+
+	require.NoError(t, client.SetThrottleDownLimit("slow", rtorrent.KiBPerSecond))
+	limit, err := client.ThrottleDownLimit("slow")
+	require.NoError(t, err)
+	require.Equal(t, rtorrent.KiBPerSecond, limit)
+	_, err = client.ThrottleUpLimit("fast")
+	require.Error(t, err)
+
+	require.NoError(t, client.CreateView("team-a", ""))
+	require.True(t, errors.Is(client.CreateView("team-a", ""), rtorrent.ErrInvalidArgument))
+	require.NoError(t, client.AddToView(torrent, "team-a"))
+	views, err := client.ListViews()
+	require.NoError(t, err)
+	require.Equal(t, []rtorrent.View{rtorrent.ViewMain, "team-a"}, views)
+	torrents, err = client.GetTorrents("team-a")
+	require.NoError(t, err)
+	require.Len(t, torrents, 1)
+	require.NoError(t, client.RemoveFromView(torrent, "team-a"))
+	require.NoError(t, client.ClearView("team-a"))
+	torrents, err = client.GetTorrents("team-a")
+	require.NoError(t, err)
+	require.Empty(t, torrents)
+
+	require.NoError(t, client.SetFilePrority(torrent, 1, 2))
+	files, err := client.GetFiles(torrent)
+	require.NoError(t, err)
+	require.Equal(t, 1, files[1].Index)
+	require.Equal(t, 2, files[1].Priority)
+	require.Error(t, client.SetFilePrority(torrent, 5, 2))
+
+	boom := errors.New("boom")
+	mock.SetError("AddTorrentContext", boom)
+	require.Equal(t, boom, client.AddTorrent([]byte("data")))
+	mock.SetError("AddTorrent", nil)
+	require.NoError(t, client.AddTorrent([]byte("data")))
+	require.Equal(t, []Call{
+		{Method: "AddTorrent", Args: []interface{}{[]byte("data")}},
+		{Method: "AddTorrent", Args: []interface{}{[]byte("data")}},
+	}, mock.CallsTo("AddTorrent"))
+	hash, err := client.AddTorrentURLWithOptions("magnet:?xt=urn:btih:b7b0fbab74a85d4ac170662c645982a862826455", rtorrent.AddOptions{Start: true})
+	require.NoError(t, err)
+	require.Equal(t, "B7B0FBAB74A85D4AC170662C645982A862826455", hash)
+	_, err = client.AddTorrentWithOptions([]byte("data"), rtorrent.AddOptions{})
+	require.Error(t, err, "invalid metainfo")
+	hash, err = client.AddMetaInfo(&metainfo.MetaInfo{InfoBytes: []byte("de")}, rtorrent.AddOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "600CCD1B71569232D01D110BC63E906BEAB04D8C", hash)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(t, context.Canceled, client.DeleteContext(ctx, torrent))
+
+	require.NoError(t, client.Delete(torrent))
+	_, err = client.GetTorrent(torrent)
+	require.Error(t, err)
+
+	require.Len(t, mock.Calls(), 40)
+	mock.Reset()
+	require.Empty(t, mock.Calls())
+}
@@ -0,0 +1,971 @@
+package rtorrenttest
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tab1293/go-rtorrent/xmlrpc"
+)
+
+type (
+	downloadCommand func(s *Server, t *Torrent, args []interface{}) (interface{}, error)
+	fileCommand     func(t *Torrent, f *File, args []interface{}) (interface{}, error)
+	trackerCommand  func(t *Torrent, tr *Tracker, args []interface{}) (interface{}, error)
+	peerCommand     func(t *Torrent, p *Peer, args []interface{}) (interface{}, error)
+)
+
+// unixTime reports tim as rTorrent does, in seconds, 0 for unset
+func unixTime(tim time.Time) int64 {
+	if tim.IsZero() {
+		return 0
+	}
+	return tim.Unix()
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// downloadCommands are the d.* commands, usable as methods with the info-hash
+// as target and as d.multicall2 columns
+var downloadCommands = map[string]downloadCommand{
+	"d.hash":      func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return t.Hash, nil },
+	"d.name":      func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return t.Name, nil },
+	"d.base_path": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return t.BasePath(), nil },
+	"d.directory": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		if t.MultiFile {
+			return t.BasePath(), nil
+		}
+		return t.Directory, nil
+	},
+	"d.size_bytes":      func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return t.Size, nil },
+	"d.completed_bytes": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return t.CompletedBytes, nil },
+	"d.left_bytes": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		return t.Size - t.CompletedBytes, nil
+	},
+	"d.complete": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		return boolInt(t.Complete()), nil
+	},
+	"d.ratio":               func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return t.Ratio(), nil },
+	"d.state":               func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return boolInt(t.Started), nil },
+	"d.is_open":             func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return boolInt(t.Open), nil },
+	"d.is_active":           func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return boolInt(t.Active()), nil },
+	"d.is_multi_file":       func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return boolInt(t.MultiFile), nil },
+	"d.down.rate":           func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return t.DownRate, nil },
+	"d.up.rate":             func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return t.UpRate, nil },
+	"d.down.total":          func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return t.DownTotal, nil },
+	"d.up.total":            func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return t.UpTotal, nil },
+	"d.peers_connected":     func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return t.PeersConnected, nil },
+	"d.peers_not_connected": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return t.PeersNotConnected, nil },
+	"d.peers_complete":      func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return t.PeersComplete, nil },
+	"d.peers_accounted":     func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return t.PeersAccounted, nil },
+	"d.hashing":             func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return t.Hashing, nil },
+	"d.chunk_size":          func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return t.ChunkSize, nil },
+	"d.size_files":          func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return len(t.Files), nil },
+	"d.priority":            func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return t.Priority, nil },
+	"d.message":             func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return t.Message, nil },
+	"d.throttle_name":       func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return t.ThrottleName, nil },
+	"d.is_private":          func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return boolInt(t.Private), nil },
+	"d.is_hash_checked": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		return boolInt(t.Hashing == 0), nil
+	},
+	"d.is_hash_checking": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		return boolInt(t.Hashing != 0), nil
+	},
+	"d.peers_min":        func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return t.PeersMin, nil },
+	"d.peers_max":        func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return t.PeersMax, nil },
+	"d.size_chunks":      func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return t.SizeChunks(), nil },
+	"d.completed_chunks": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return t.CompletedChunks(), nil },
+	"d.tied_to_file":     func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return t.TiedToFile, nil },
+	"d.creation_date": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		return unixTime(t.CreationDate), nil
+	},
+	"d.load_date": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return unixTime(t.LoadDate), nil },
+	"d.timestamp.started": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		return unixTime(t.StartedAt), nil
+	},
+	"d.timestamp.finished": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		return unixTime(t.FinishedAt), nil
+	},
+	"d.custom": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		key, err := stringArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return t.Custom[key], nil
+	},
+	"d.custom.set": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		key, err := stringArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		value, err := stringArg(args, 1)
+		if err != nil {
+			return nil, err
+		}
+		t.Custom[key] = value
+		return int64(0), nil
+	},
+	"d.directory.set": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		dir, err := stringArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		t.Directory = dir
+		return int64(0), nil
+	},
+	"d.priority.set": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		p, err := intArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		if p < 0 || p > 3 {
+			return nil, invalidArgument("Priority out of range.")
+		}
+		t.Priority = int(p)
+		return int64(0), nil
+	},
+	"d.throttle_name.set": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		name, err := stringArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		if t.Started {
+			return nil, invalidArgument("Torrent must be stopped to change its throttle.")
+		}
+		t.ThrottleName = name
+		return int64(0), nil
+	},
+	"d.message.set": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		msg, err := stringArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		t.Message = msg
+		return int64(0), nil
+	},
+	"d.start": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		t.start()
+		return int64(0), nil
+	},
+	"d.stop": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		t.Started = false
+		return int64(0), nil
+	},
+	"d.open": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		t.Open = true
+		return int64(0), nil
+	},
+	"d.close": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		t.Open, t.Started = false, false
+		return int64(0), nil
+	},
+	"d.erase": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		s.remove(t.Hash)
+		return int64(0), nil
+	},
+	"d.tracker.insert": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		group, err := intArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		url, err := stringArg(args, 1)
+		if err != nil {
+			return nil, err
+		}
+		if group < 0 || group > 32 {
+			return nil, invalidArgument("Tracker group number invalid.")
+		}
+		t.Trackers = append(t.Trackers, Tracker{URL: url, Group: int(group), Enabled: true})
+		return int64(0), nil
+	},
+	"d.tracker_announce": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		now := time.Now()
+		for i := range t.Trackers {
+			if tr := &t.Trackers[i]; tr.Enabled {
+				tr.LastAnnounce, tr.LastSuccess = now, now
+				tr.SuccessCounter++
+			}
+		}
+		return int64(0), nil
+	},
+	"d.tracker_size": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) { return len(t.Trackers), nil },
+	"d.views": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		views := make([]interface{}, len(t.Views))
+		for i, v := range t.Views {
+			views[i] = v
+		}
+		return views, nil
+	},
+	"d.views.has": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		name, err := stringArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return boolInt(t.inView(name)), nil
+	},
+	"d.views.push_back_unique": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		name, err := stringArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		if !t.inView(name) {
+			t.Views = append(t.Views, name)
+		}
+		return int64(0), nil
+	},
+	"d.views.remove": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		name, err := stringArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range t.Views {
+			if v == name {
+				t.Views = append(t.Views[:i], t.Views[i+1:]...)
+				break
+			}
+		}
+		return int64(0), nil
+	},
+	"d.check_hash": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		return int64(0), nil
+	},
+	"d.update_priorities": func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+		return int64(0), nil
+	},
+}
+
+func init() {
+	for i := 1; i <= 5; i++ {
+		key := strconv.Itoa(i)
+		downloadCommands["d.custom"+key] = func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+			return t.Custom[key], nil
+		}
+		downloadCommands["d.custom"+key+".set"] = func(s *Server, t *Torrent, args []interface{}) (interface{}, error) {
+			value, err := stringArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			t.Custom[key] = value
+			return int64(0), nil
+		}
+	}
+}
+
+// fileCommands are the f.* commands, usable as methods with "HASH:fN" as
+// target and as f.multicall columns
+var fileCommands = map[string]fileCommand{
+	"f.path":             func(t *Torrent, f *File, args []interface{}) (interface{}, error) { return f.Path, nil },
+	"f.frozen_path":      func(t *Torrent, f *File, args []interface{}) (interface{}, error) { return frozenPath(t, f), nil },
+	"f.size_bytes":       func(t *Torrent, f *File, args []interface{}) (interface{}, error) { return f.Size, nil },
+	"f.size_chunks":      func(t *Torrent, f *File, args []interface{}) (interface{}, error) { return f.SizeChunks, nil },
+	"f.completed_chunks": func(t *Torrent, f *File, args []interface{}) (interface{}, error) { return f.CompletedChunks, nil },
+	"f.priority":         func(t *Torrent, f *File, args []interface{}) (interface{}, error) { return f.Priority, nil },
+	"f.priority.set": func(t *Torrent, f *File, args []interface{}) (interface{}, error) {
+		p, err := intArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		if p < 0 || p > 2 {
+			return nil, invalidArgument("Invalid priority.")
+		}
+		f.Priority = int(p)
+		return int64(0), nil
+	},
+}
+
+// trackerCommands are the t.* commands, usable as methods with "HASH:tN" as
+// target and as t.multicall columns
+var trackerCommands = map[string]trackerCommand{
+	"t.url":   func(t *Torrent, tr *Tracker, args []interface{}) (interface{}, error) { return tr.URL, nil },
+	"t.group": func(t *Torrent, tr *Tracker, args []interface{}) (interface{}, error) { return tr.Group, nil },
+	"t.type":  func(t *Torrent, tr *Tracker, args []interface{}) (interface{}, error) { return tr.Type(), nil },
+	"t.is_enabled": func(t *Torrent, tr *Tracker, args []interface{}) (interface{}, error) {
+		return boolInt(tr.Enabled), nil
+	},
+	"t.scrape_complete": func(t *Torrent, tr *Tracker, args []interface{}) (interface{}, error) { return tr.ScrapeComplete, nil },
+	"t.scrape_incomplete": func(t *Torrent, tr *Tracker, args []interface{}) (interface{}, error) {
+		return tr.ScrapeIncomplete, nil
+	},
+	"t.scrape_downloaded": func(t *Torrent, tr *Tracker, args []interface{}) (interface{}, error) {
+		return tr.ScrapeDownloaded, nil
+	},
+	"t.activity_time_last": func(t *Torrent, tr *Tracker, args []interface{}) (interface{}, error) {
+		return unixTime(tr.LastAnnounce), nil
+	},
+	"t.success_time_last": func(t *Torrent, tr *Tracker, args []interface{}) (interface{}, error) {
+		return unixTime(tr.LastSuccess), nil
+	},
+	"t.success_counter": func(t *Torrent, tr *Tracker, args []interface{}) (interface{}, error) { return tr.SuccessCounter, nil },
+	"t.failed_counter":  func(t *Torrent, tr *Tracker, args []interface{}) (interface{}, error) { return tr.FailedCounter, nil },
+	"t.is_enabled.set": func(t *Torrent, tr *Tracker, args []interface{}) (interface{}, error) {
+		enabled, err := intArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		tr.Enabled = enabled != 0
+		return int64(0), nil
+	},
+}
+
+// peerCommands are the p.* commands, usable as methods with "HASH:pID" as
+// target and as p.multicall columns
+var peerCommands = map[string]peerCommand{
+	"p.id":                func(t *Torrent, p *Peer, args []interface{}) (interface{}, error) { return p.ID, nil },
+	"p.address":           func(t *Torrent, p *Peer, args []interface{}) (interface{}, error) { return p.Address, nil },
+	"p.port":              func(t *Torrent, p *Peer, args []interface{}) (interface{}, error) { return p.Port, nil },
+	"p.client_version":    func(t *Torrent, p *Peer, args []interface{}) (interface{}, error) { return p.ClientVersion, nil },
+	"p.completed_percent": func(t *Torrent, p *Peer, args []interface{}) (interface{}, error) { return p.CompletedPercent, nil },
+	"p.down_rate":         func(t *Torrent, p *Peer, args []interface{}) (interface{}, error) { return p.DownRate, nil },
+	"p.up_rate":           func(t *Torrent, p *Peer, args []interface{}) (interface{}, error) { return p.UpRate, nil },
+	"p.down_total":        func(t *Torrent, p *Peer, args []interface{}) (interface{}, error) { return p.DownTotal, nil },
+	"p.up_total":          func(t *Torrent, p *Peer, args []interface{}) (interface{}, error) { return p.UpTotal, nil },
+	"p.is_encrypted":      func(t *Torrent, p *Peer, args []interface{}) (interface{}, error) { return boolInt(p.Encrypted), nil },
+	"p.is_incoming":       func(t *Torrent, p *Peer, args []interface{}) (interface{}, error) { return boolInt(p.Incoming), nil },
+	"p.is_snubbed":        func(t *Torrent, p *Peer, args []interface{}) (interface{}, error) { return boolInt(p.Snubbed), nil },
+	"p.is_banned":         func(t *Torrent, p *Peer, args []interface{}) (interface{}, error) { return boolInt(p.Banned), nil },
+	"p.snubbed.set": func(t *Torrent, p *Peer, args []interface{}) (interface{}, error) {
+		snubbed, err := intArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		p.Snubbed = snubbed != 0
+		return int64(0), nil
+	},
+	"p.banned.set": func(t *Torrent, p *Peer, args []interface{}) (interface{}, error) {
+		banned, err := intArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		p.Banned = banned != 0
+		return int64(0), nil
+	},
+	"p.disconnect": func(t *Torrent, p *Peer, args []interface{}) (interface{}, error) {
+		for i := range t.Peers {
+			if t.Peers[i].ID == p.ID {
+				t.Peers = append(t.Peers[:i], t.Peers[i+1:]...)
+				break
+			}
+		}
+		return int64(0), nil
+	},
+}
+
+func frozenPath(t *Torrent, f *File) string {
+	if t.MultiFile {
+		return path.Join(t.BasePath(), f.Path)
+	}
+	return t.BasePath()
+}
+
+// builtinViews are the views every rTorrent instance has, in the order
+// view.list reports them
+var builtinViews = []struct {
+	name   string
+	filter func(t *Torrent) bool
+}{
+	{"main", func(t *Torrent) bool { return true }},
+	{"default", func(t *Torrent) bool { return true }},
+	{"name", func(t *Torrent) bool { return true }},
+	{"active", func(t *Torrent) bool { return t.Active() }},
+	{"started", func(t *Torrent) bool { return t.Started }},
+	{"stopped", func(t *Torrent) bool { return !t.Started }},
+	{"complete", func(t *Torrent) bool { return t.Complete() }},
+	{"incomplete", func(t *Torrent) bool { return !t.Complete() }},
+	{"hashing", func(t *Torrent) bool { return t.Hashing != 0 }},
+	{"seeding", func(t *Torrent) bool { return t.Started && t.Complete() }},
+	{"leeching", func(t *Torrent) bool { return t.Started && !t.Complete() }},
+}
+
+// customView is a view added with view.add, holding the torrents for which
+// the filter command, set with view.filter, returns a non zero value. An
+// empty filter matches every torrent, as in rTorrent.
+type customView struct {
+	name   string
+	filter string
+}
+
+// view returns the filter of the view `name`
+func (s *Server) view(name string) (func(t *Torrent) bool, error) {
+	for _, v := range builtinViews {
+		if v.name == name {
+			return v.filter, nil
+		}
+	}
+	for _, v := range s.views {
+		if v.name == name {
+			filter := v.filter
+			return func(t *Torrent) bool { return s.matches(t, filter) }, nil
+		}
+	}
+	return nil, invalidArgument("Could not find view: " + name)
+}
+
+// matches evaluates the view filter command `filter` against t. Only single
+// commands are supported, as well as "true=" and "false=".
+func (s *Server) matches(t *Torrent, filter string) bool {
+	switch name, _ := parseCommand(filter); name {
+	case "", "true":
+		return true
+	case "false":
+		return false
+	}
+	v, err := s.runDownloadCommand(t, filter)
+	if err != nil {
+		return false
+	}
+	switch v := v.(type) {
+	case int64:
+		return v != 0
+	case int:
+		return v != 0
+	case string:
+		return v != ""
+	}
+	return false
+}
+
+func torrentNotFound() error {
+	return &xmlrpc.Fault{Code: xmlrpc.FaultInvalidArgument, Message: "Could not find info-hash."}
+}
+
+func invalidArgument(msg string) error {
+	return &xmlrpc.Fault{Code: xmlrpc.FaultInvalidArgument, Message: msg}
+}
+
+func stringArg(args []interface{}, i int) (string, error) {
+	if i >= len(args) {
+		return "", invalidArgument("Wrong argument count.")
+	}
+	switch v := args[i].(type) {
+	case string:
+		return v, nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	}
+	return "", invalidArgument("Not a string.")
+}
+
+// intArg accepts integers as well as their string form, as used in
+// multicall columns and post-load commands
+func intArg(args []interface{}, i int) (int64, error) {
+	if i >= len(args) {
+		return 0, invalidArgument("Wrong argument count.")
+	}
+	switch v := args[i].(type) {
+	case int64:
+		return v, nil
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, invalidArgument("Not a value.")
+		}
+		return n, nil
+	}
+	return 0, invalidArgument("Not a value.")
+}
+
+// parseCommand splits a multicall column or post-load command such as
+// "d.custom=key" or "d.custom1.set=label" into its name and arguments.
+// Arguments may be double quoted, with backslash escapes, to hold commas.
+func parseCommand(cmd string) (string, []interface{}) {
+	parts := strings.SplitN(cmd, "=", 2)
+	if len(parts) == 1 || parts[1] == "" {
+		return parts[0], nil
+	}
+	var (
+		args    []interface{}
+		arg     strings.Builder
+		quoted  bool
+		escaped bool
+	)
+	for _, c := range parts[1] {
+		switch {
+		case escaped:
+			arg.WriteRune(c)
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case !quoted && c == ',':
+			args = append(args, arg.String())
+			arg.Reset()
+		default:
+			arg.WriteRune(c)
+		}
+	}
+	return parts[0], append(args, arg.String())
+}
+
+// runDownloadCommand runs the command string `cmd` against t
+func (s *Server) runDownloadCommand(t *Torrent, cmd string) (interface{}, error) {
+	name, args := parseCommand(cmd)
+	c, ok := downloadCommands[name]
+	if !ok {
+		return nil, &xmlrpc.Fault{Code: xmlrpc.FaultMethodNotFound, Message: fmt.Sprintf("Command \"%s\" does not exist.", name)}
+	}
+	return c(s, t, args)
+}
+
+// runTrackerCommand runs the command string `cmd` against the tracker tr of t
+func runTrackerCommand(t *Torrent, tr *Tracker, cmd string) (interface{}, error) {
+	name, args := parseCommand(cmd)
+	c, ok := trackerCommands[name]
+	if !ok {
+		return nil, &xmlrpc.Fault{Code: xmlrpc.FaultMethodNotFound, Message: fmt.Sprintf("Command \"%s\" does not exist.", name)}
+	}
+	return c(t, tr, args)
+}
+
+// runPeerCommand runs the command string `cmd` against the peer p of t
+func runPeerCommand(t *Torrent, p *Peer, cmd string) (interface{}, error) {
+	name, args := parseCommand(cmd)
+	c, ok := peerCommands[name]
+	if !ok {
+		return nil, &xmlrpc.Fault{Code: xmlrpc.FaultMethodNotFound, Message: fmt.Sprintf("Command \"%s\" does not exist.", name)}
+	}
+	return c(t, p, args)
+}
+
+// runFileCommand runs the command string `cmd` against the file f of t
+func runFileCommand(t *Torrent, f *File, cmd string) (interface{}, error) {
+	name, args := parseCommand(cmd)
+	c, ok := fileCommands[name]
+	if !ok {
+		return nil, &xmlrpc.Fault{Code: xmlrpc.FaultMethodNotFound, Message: fmt.Sprintf("Command \"%s\" does not exist.", name)}
+	}
+	return c(t, f, args)
+}
+
+// target resolves the info-hash given as first argument
+func (s *Server) target(args []interface{}) (*Torrent, error) {
+	hash, err := stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	t := s.find(hash)
+	if t == nil {
+		return nil, torrentNotFound()
+	}
+	return t, nil
+}
+
+// itemTarget resolves a "HASH:<kind>N" target, such as "HASH:f0", to a
+// torrent and the index of one of its `count` files or trackers
+func (s *Server) itemTarget(args []interface{}, kind string, count func(t *Torrent) int) (*Torrent, int, error) {
+	target, err := stringArg(args, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	parts := strings.SplitN(target, ":"+kind, 2)
+	if len(parts) != 2 {
+		return nil, 0, invalidArgument("Unsupported target type found.")
+	}
+	t := s.find(parts[0])
+	if t == nil {
+		return nil, 0, torrentNotFound()
+	}
+	i, err := strconv.Atoi(parts[1])
+	if err != nil || i < 0 || i >= count(t) {
+		return nil, 0, invalidArgument("Unsupported target type found.")
+	}
+	return t, i, nil
+}
+
+// fileTarget resolves a "HASH:fN" target
+func (s *Server) fileTarget(args []interface{}) (*Torrent, *File, error) {
+	t, i, err := s.itemTarget(args, "f", func(t *Torrent) int { return len(t.Files) })
+	if err != nil {
+		return nil, nil, err
+	}
+	return t, &t.Files[i], nil
+}
+
+// trackerTarget resolves a "HASH:tN" target
+func (s *Server) trackerTarget(args []interface{}) (*Torrent, *Tracker, error) {
+	t, i, err := s.itemTarget(args, "t", func(t *Torrent) int { return len(t.Trackers) })
+	if err != nil {
+		return nil, nil, err
+	}
+	return t, &t.Trackers[i], nil
+}
+
+// peerTarget resolves a "HASH:pID" target
+func (s *Server) peerTarget(args []interface{}) (*Torrent, *Peer, error) {
+	target, err := stringArg(args, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	parts := strings.SplitN(target, ":p", 2)
+	if len(parts) != 2 {
+		return nil, nil, invalidArgument("Unsupported target type found.")
+	}
+	t := s.find(parts[0])
+	if t == nil {
+		return nil, nil, torrentNotFound()
+	}
+	for i := range t.Peers {
+		if strings.EqualFold(t.Peers[i].ID, parts[1]) {
+			return t, &t.Peers[i], nil
+		}
+	}
+	return nil, nil, invalidArgument("Could not find peer.")
+}
+
+func (s *Server) registerMethods() {
+	for name, c := range downloadCommands {
+		c := c
+		s.register(name, func(args ...interface{}) (interface{}, error) {
+			t, err := s.target(args)
+			if err != nil {
+				return nil, err
+			}
+			return c(s, t, args[1:])
+		})
+	}
+	for name, c := range fileCommands {
+		c := c
+		s.register(name, func(args ...interface{}) (interface{}, error) {
+			t, f, err := s.fileTarget(args)
+			if err != nil {
+				return nil, err
+			}
+			return c(t, f, args[1:])
+		})
+	}
+
+	for name, c := range trackerCommands {
+		c := c
+		s.register(name, func(args ...interface{}) (interface{}, error) {
+			t, tr, err := s.trackerTarget(args)
+			if err != nil {
+				return nil, err
+			}
+			return c(t, tr, args[1:])
+		})
+	}
+
+	for name, c := range peerCommands {
+		c := c
+		s.register(name, func(args ...interface{}) (interface{}, error) {
+			t, p, err := s.peerTarget(args)
+			if err != nil {
+				return nil, err
+			}
+			return c(t, p, args[1:])
+		})
+	}
+
+	s.register("d.multicall2", s.downloadMulticall)
+	s.register("f.multicall", s.fileMulticall)
+	s.register("t.multicall", s.trackerMulticall)
+	s.register("p.multicall", s.peerMulticall)
+
+	for _, name := range []string{"load.normal", "load.verbose", "load.start", "load.start_verbose"} {
+		start := strings.HasPrefix(name, "load.start")
+		s.rpc.Register(name, func(args ...interface{}) (interface{}, error) {
+			url, err := stringArg(args, 1)
+			if err != nil {
+				return nil, err
+			}
+			data, err := s.fetch(url)
+			if err != nil {
+				return nil, invalidArgument(err.Error())
+			}
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return s.load(data, start, args[2:])
+		})
+	}
+	for _, name := range []string{"load.raw", "load.raw_verbose", "load.raw_start", "load.raw_start_verbose"} {
+		start := strings.HasPrefix(name, "load.raw_start")
+		s.register(name, func(args ...interface{}) (interface{}, error) {
+			if len(args) < 2 {
+				return nil, invalidArgument("Wrong argument count.")
+			}
+			var data []byte
+			switch raw := args[1].(type) {
+			case []byte:
+				data = raw
+			case string: // JSON-RPC carries raw data base64 encoded
+				var err error
+				if data, err = base64.StdEncoding.DecodeString(raw); err != nil {
+					return nil, invalidArgument("Invalid base64 raw data.")
+				}
+			default:
+				return nil, invalidArgument("Unsupported type for raw data.")
+			}
+			return s.load(data, start, args[2:])
+		})
+	}
+
+	s.register("view.list", func(args ...interface{}) (interface{}, error) {
+		views := []interface{}{}
+		for _, v := range builtinViews {
+			views = append(views, v.name)
+		}
+		for _, v := range s.views {
+			views = append(views, v.name)
+		}
+		return views, nil
+	})
+	s.register("view.add", func(args ...interface{}) (interface{}, error) {
+		name, err := stringArg(args, len(args)-1)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.view(name); err == nil {
+			return nil, invalidArgument("View with same name already inserted.")
+		}
+		s.views = append(s.views, customView{name: name})
+		return int64(0), nil
+	})
+	s.register("view.filter", func(args ...interface{}) (interface{}, error) {
+		name, err := stringArg(args, 1)
+		if err != nil {
+			return nil, err
+		}
+		filter, err := stringArg(args, 2)
+		if err != nil {
+			return nil, err
+		}
+		for i := range s.views {
+			if s.views[i].name == name {
+				s.views[i].filter = filter
+				return int64(0), nil
+			}
+		}
+		if _, err := s.view(name); err != nil {
+			return nil, err
+		}
+		return nil, invalidArgument("Cannot change the filter of a built-in view.")
+	})
+	for _, name := range []string{"view.set_visible", "view.set_not_visible"} {
+		s.register(name, func(args ...interface{}) (interface{}, error) {
+			if _, err := s.target(args); err != nil {
+				return nil, err
+			}
+			view, err := stringArg(args, 1)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := s.view(view); err != nil {
+				return nil, err
+			}
+			// views are filtered on every d.multicall2, nothing to do
+			return int64(0), nil
+		})
+	}
+
+	s.register("system.hostname", func(args ...interface{}) (interface{}, error) {
+		return s.global.Hostname, nil
+	})
+	s.register("network.bind_address", func(args ...interface{}) (interface{}, error) {
+		return s.global.BindAddress, nil
+	})
+	s.register("directory.default", func(args ...interface{}) (interface{}, error) {
+		return s.global.Directory, nil
+	})
+	s.register("directory.default.set", func(args ...interface{}) (interface{}, error) {
+		dir, err := stringArg(args, len(args)-1)
+		if err != nil {
+			return nil, err
+		}
+		s.global.Directory = dir
+		return int64(0), nil
+	})
+	s.register("session.path.set", func(args ...interface{}) (interface{}, error) {
+		dir, err := stringArg(args, len(args)-1)
+		if err != nil {
+			return nil, err
+		}
+		s.global.SessionPath = dir
+		return int64(0), nil
+	})
+	s.register("system.shutdown.normal", func(args ...interface{}) (interface{}, error) {
+		s.global.Shutdown = true
+		return int64(0), nil
+	})
+	for name, value := range map[string]*int64{
+		"throttle.global_down.max_rate": &s.global.DownLimit,
+		"throttle.global_up.max_rate":   &s.global.UpLimit,
+		"throttle.max_peers.normal":     &s.global.MaxPeers,
+		"throttle.max_peers.seed":       &s.global.MaxPeersSeed,
+		"throttle.max_uploads":          &s.global.MaxUploads,
+		"throttle.max_uploads.global":   &s.global.MaxUploadsGlobal,
+	} {
+		value := value
+		s.register(name, func(args ...interface{}) (interface{}, error) {
+			return *value, nil
+		})
+		s.register(name+".set", func(args ...interface{}) (interface{}, error) {
+			n, err := intArg(args, len(args)-1)
+			if err != nil {
+				return nil, err
+			}
+			*value = n
+			return int64(0), nil
+		})
+	}
+	for _, dir := range []string{"down", "up"} {
+		limit := func(t *Throttle) *int64 { return &t.DownLimit }
+		if dir == "up" {
+			limit = func(t *Throttle) *int64 { return &t.UpLimit }
+		}
+		s.register("throttle."+dir, func(args ...interface{}) (interface{}, error) {
+			name, err := stringArg(args, 1)
+			if err != nil {
+				return nil, err
+			}
+			kb, err := intArg(args, 2)
+			if err != nil {
+				return nil, err
+			}
+			if name == "" || name == "NULL" {
+				return nil, invalidArgument("Invalid throttle name.")
+			}
+			t := s.global.Throttles[name]
+			*limit(&t) = kb * 1024
+			s.global.Throttles[name] = t
+			return int64(0), nil
+		})
+		s.register("throttle."+dir+".max", func(args ...interface{}) (interface{}, error) {
+			name, err := stringArg(args, 1)
+			if err != nil {
+				return nil, err
+			}
+			t, ok := s.global.Throttles[name]
+			if !ok {
+				return int64(-1), nil
+			}
+			return *limit(&t), nil
+		})
+	}
+	s.register("throttle.global_down.rate", func(args ...interface{}) (interface{}, error) {
+		return s.global.DownRate, nil
+	})
+	s.register("throttle.global_up.rate", func(args ...interface{}) (interface{}, error) {
+		return s.global.UpRate, nil
+	})
+	s.register("throttle.global_down.total", func(args ...interface{}) (interface{}, error) {
+		return s.global.DownTotal, nil
+	})
+	s.register("throttle.global_up.total", func(args ...interface{}) (interface{}, error) {
+		return s.global.UpTotal, nil
+	})
+}
+
+// load adds a torrent, then runs the post-load commands `cmds` against it.
+// The torrent is removed again if one of them fails.
+func (s *Server) load(data []byte, start bool, cmds []interface{}) (interface{}, error) {
+	t, err := parseMetainfo(data)
+	if err != nil {
+		return nil, invalidArgument("Could not create download: " + err.Error())
+	}
+	if err := s.insert(t); err != nil {
+		return nil, err
+	}
+	for i := range cmds {
+		cmd, err := stringArg(cmds, i)
+		if err != nil {
+			s.remove(t.Hash)
+			return nil, err
+		}
+		if _, err := s.runDownloadCommand(t, cmd); err != nil {
+			s.remove(t.Hash)
+			return nil, err
+		}
+	}
+	if start {
+		t.start()
+	}
+	return int64(0), nil
+}
+
+// downloadMulticall implements d.multicall2: "", view, commands...
+func (s *Server) downloadMulticall(args ...interface{}) (interface{}, error) {
+	view, err := stringArg(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	inView, err := s.view(view)
+	if err != nil {
+		return nil, err
+	}
+	cmds := args[2:]
+	rows := []interface{}{}
+	for _, t := range append([]*Torrent(nil), s.torrents...) {
+		if !inView(t) {
+			continue
+		}
+		row := make([]interface{}, len(cmds))
+		for i := range cmds {
+			cmd, err := stringArg(cmds, i)
+			if err != nil {
+				return nil, err
+			}
+			if row[i], err = s.runDownloadCommand(t, cmd); err != nil {
+				return nil, err
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// fileMulticall implements f.multicall: hash, pattern, commands...
+func (s *Server) fileMulticall(args ...interface{}) (interface{}, error) {
+	return s.itemMulticall(args, func(t *Torrent) int { return len(t.Files) }, func(t *Torrent, i int, cmd string) (interface{}, error) {
+		return runFileCommand(t, &t.Files[i], cmd)
+	})
+}
+
+// trackerMulticall implements t.multicall: hash, pattern, commands...
+func (s *Server) trackerMulticall(args ...interface{}) (interface{}, error) {
+	return s.itemMulticall(args, func(t *Torrent) int { return len(t.Trackers) }, func(t *Torrent, i int, cmd string) (interface{}, error) {
+		return runTrackerCommand(t, &t.Trackers[i], cmd)
+	})
+}
+
+// peerMulticall implements p.multicall: hash, pattern, commands...
+func (s *Server) peerMulticall(args ...interface{}) (interface{}, error) {
+	return s.itemMulticall(args, func(t *Torrent) int { return len(t.Peers) }, func(t *Torrent, i int, cmd string) (interface{}, error) {
+		return runPeerCommand(t, &t.Peers[i], cmd)
+	})
+}
+
+// itemMulticall runs commands against each of the `count` items of the
+// torrent targeted by args, with `run`
+func (s *Server) itemMulticall(args []interface{}, count func(t *Torrent) int, run func(t *Torrent, i int, cmd string) (interface{}, error)) (interface{}, error) {
+	t, err := s.target(args)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) < 2 {
+		return nil, invalidArgument("Wrong argument count.")
+	}
+	cmds := args[2:]
+	rows := []interface{}{}
+	for i := 0; i < count(t); i++ {
+		row := make([]interface{}, len(cmds))
+		for j := range cmds {
+			cmd, err := stringArg(cmds, j)
+			if err != nil {
+				return nil, err
+			}
+			if row[j], err = run(t, i, cmd); err != nil {
+				return nil, err
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
@@ -0,0 +1,296 @@
+// Package rtorrenttest provides an in-memory rTorrent for tests.
+//
+// A Server speaks rTorrent's XML-RPC protocol over HTTP (NewServer) or SCGI
+// (NewSCGIServer), as well as JSON-RPC on the same endpoint, and keeps a
+// simulated download list that understands the commands used by the rtorrent
+// package: d.multicall2, f.multicall, t.multicall, p.multicall, load.raw and
+// load.normal (with post-load commands), d.start, d.stop, d.close, d.erase,
+// f.priority.set, the tracker and peer commands, the global throttles and
+// throttle groups and the system.* methods, system.multicall included.
+// Nothing is ever downloaded: tests script progress with Server.Update.
+//
+//	srv := rtorrenttest.NewServer()
+//	defer srv.Close()
+//	client := rtorrent.New(srv.URL, false)
+//	jsonClient := rtorrent.New(jsonrpc.SchemePrefix+srv.URL, false)
+package rtorrenttest
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tab1293/go-rtorrent/jsonrpc"
+	"github.com/tab1293/go-rtorrent/xmlrpc"
+)
+
+// Global is the simulated state of the rTorrent instance itself
+type Global struct {
+	// Hostname is reported by system.hostname
+	Hostname string
+	// BindAddress is reported by network.bind_address
+	BindAddress string
+	// Directory is directory.default, where loaded torrents are stored
+	Directory string
+	// SessionPath is set by session.path.set
+	SessionPath string
+	// DownRate, UpRate, DownTotal and UpTotal are reported by the
+	// throttle.global_{down,up}.{rate,total} commands
+	DownRate  int64
+	UpRate    int64
+	DownTotal int64
+	UpTotal   int64
+	// DownLimit and UpLimit are throttle.global_{down,up}.max_rate, in bytes
+	DownLimit int64
+	UpLimit   int64
+	// Throttles are the throttle groups defined by throttle.down and
+	// throttle.up, by name
+	Throttles map[string]Throttle
+	// MaxPeers, MaxPeersSeed, MaxUploads and MaxUploadsGlobal are
+	// throttle.max_peers.{normal,seed} and throttle.max_uploads{,.global}
+	MaxPeers         int64
+	MaxPeersSeed     int64
+	MaxUploads       int64
+	MaxUploadsGlobal int64
+	// Shutdown is set once system.shutdown.normal has been called
+	Shutdown bool
+}
+
+// Throttle is a throttle group, limiting the rates of the torrents assigned
+// to it with d.throttle_name.set
+type Throttle struct {
+	// DownLimit and UpLimit are in bytes
+	DownLimit int64
+	UpLimit   int64
+}
+
+// Server is an in-memory rTorrent serving XML-RPC
+type Server struct {
+	// URL is the endpoint of the server, to be passed to rtorrent.New
+	URL string
+
+	rpc      *xmlrpc.Server
+	mu       sync.Mutex
+	global   Global
+	torrents []*Torrent
+	// views are the filters of the custom views, in creation order
+	views []customView
+	urls  map[string][]byte
+	calls []string
+	close func()
+}
+
+// NewServer starts and returns a new Server speaking XML-RPC over HTTP.
+// The caller should call Close when finished, to shut it down.
+func NewServer() *Server {
+	s := newServer()
+	srv := httptest.NewServer(s)
+	s.URL = srv.URL + "/RPC2"
+	s.close = srv.Close
+	return s
+}
+
+// NewSCGIServer starts and returns a new Server speaking XML-RPC over SCGI
+// on a loopback TCP port, like rTorrent's network.scgi.open_port.
+// The caller should call Close when finished, to shut it down.
+func NewSCGIServer() *Server {
+	s := newServer()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("rtorrenttest: failed to listen on a port: %v", err))
+	}
+	go xmlrpc.ServeSCGI(l, s)
+	s.URL = xmlrpc.SCGIScheme + "://" + l.Addr().String()
+	s.close = func() { l.Close() }
+	return s
+}
+
+func newServer() *Server {
+	s := &Server{
+		global: Global{
+			Hostname:     "rtorrenttest",
+			BindAddress:  "0.0.0.0",
+			Directory:    "/downloads",
+			Throttles:    map[string]Throttle{},
+			MaxPeers:     100,
+			MaxPeersSeed: -1,
+			MaxUploads:   50,
+		},
+		rpc:  xmlrpc.NewServer(),
+		urls: map[string][]byte{},
+	}
+	s.registerMethods()
+	for _, name := range s.rpc.Methods() {
+		m, _ := s.rpc.Lookup(name)
+		s.rpc.Register(name, s.record(name, m))
+	}
+	return s
+}
+
+// Close shuts down the server
+func (s *Server) Close() {
+	s.close()
+}
+
+// ServeHTTP answers a single XML-RPC request, or a JSON-RPC request or
+// batch if sent as application/json
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		jsonrpc.Handler(s.rpc.Call).ServeHTTP(w, r)
+		return
+	}
+	s.rpc.ServeHTTP(w, r)
+}
+
+// Calls returns the names of all the known methods called so far, in
+// order; calls made within a system.multicall are listed after it
+func (s *Server) Calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.calls...)
+}
+
+// Global returns a snapshot of the simulated instance state
+func (s *Server) Global() Global {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g := s.global
+	g.Throttles = make(map[string]Throttle, len(s.global.Throttles))
+	for name, t := range s.global.Throttles {
+		g.Throttles[name] = t
+	}
+	return g
+}
+
+// UpdateGlobal changes the simulated instance state
+func (s *Server) UpdateGlobal(fn func(g *Global)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.global)
+}
+
+// AddTorrent loads the .torrent `data` into the download list, stopped,
+// as load.raw would, and returns its info-hash
+func (s *Server) AddTorrent(data []byte) (string, error) {
+	t, err := parseMetainfo(data)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.insert(t); err != nil {
+		return "", err
+	}
+	return t.Hash, nil
+}
+
+// Torrent returns a snapshot of the torrent with the given hash
+func (s *Server) Torrent(hash string) (Torrent, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.find(hash)
+	if t == nil {
+		return Torrent{}, false
+	}
+	return t.clone(), true
+}
+
+// Torrents returns a snapshot of the whole download list
+func (s *Server) Torrents() []Torrent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	torrents := make([]Torrent, len(s.torrents))
+	for i, t := range s.torrents {
+		torrents[i] = t.clone()
+	}
+	return torrents
+}
+
+// Update changes the torrent with the given hash, e.g. to simulate download
+// progress. It returns false if there is no such torrent.
+func (s *Server) Update(hash string, fn func(t *Torrent)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.find(hash)
+	if t == nil {
+		return false
+	}
+	fn(t)
+	return true
+}
+
+// RegisterURL makes load.normal and friends serve `data` for `url`. Loading
+// any other URL fails, the server doesn't access the network.
+func (s *Server) RegisterURL(url string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.urls[url] = data
+}
+
+func (s *Server) find(hash string) *Torrent {
+	for _, t := range s.torrents {
+		if t.Hash == hash {
+			return t
+		}
+	}
+	return nil
+}
+
+func (s *Server) insert(t *Torrent) error {
+	if s.find(t.Hash) != nil {
+		return invalidArgument("Info hash already used by another torrent.")
+	}
+	if t.Directory == "" {
+		t.Directory = s.global.Directory
+	}
+	if t.LoadDate.IsZero() {
+		t.LoadDate = time.Now()
+	}
+	s.torrents = append(s.torrents, t)
+	return nil
+}
+
+func (s *Server) remove(hash string) {
+	for i, t := range s.torrents {
+		if t.Hash == hash {
+			s.torrents = append(s.torrents[:i], s.torrents[i+1:]...)
+			return
+		}
+	}
+}
+
+// fetch returns the registered data for url. The server never reaches the
+// network: other URLs fail.
+func (s *Server) fetch(url string) ([]byte, error) {
+	s.mu.Lock()
+	data, ok := s.urls[url]
+	s.mu.Unlock()
+	if !ok {
+		return nil, errors.Errorf("Could not fetch %s: URL not registered.", url)
+	}
+	return data, nil
+}
+
+// register adds a method that runs with the server lock held
+func (s *Server) register(name string, m xmlrpc.MethodFunc) {
+	s.rpc.Register(name, func(args ...interface{}) (interface{}, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return m(args...)
+	})
+}
+
+// record wraps m so that its calls are listed by Calls
+func (s *Server) record(name string, m xmlrpc.MethodFunc) xmlrpc.MethodFunc {
+	return func(args ...interface{}) (interface{}, error) {
+		s.mu.Lock()
+		s.calls = append(s.calls, name)
+		s.mu.Unlock()
+		return m(args...)
+	}
+}
@@ -0,0 +1,220 @@
+package rtorrenttest
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	"github.com/tab1293/go-rtorrent/metainfo"
+)
+
+// Torrent is the simulated state of a download held by the fake rTorrent.
+// Tests can inspect it through Server.Torrent and script it with Server.Update.
+type Torrent struct {
+	Hash              string
+	Name              string
+	Directory         string
+	Size              int64
+	CompletedBytes    int64
+	ChunkSize         int64
+	MultiFile         bool
+	Started           bool
+	Open              bool
+	Hashing           int
+	DownRate          int64
+	UpRate            int64
+	DownTotal         int64
+	UpTotal           int64
+	PeersConnected    int
+	PeersNotConnected int
+	PeersComplete     int
+	PeersAccounted    int
+	Priority          int
+	Message           string
+	ThrottleName      string
+	Private           bool
+	PeersMin          int
+	PeersMax          int
+	TiedToFile        string
+	// CreationDate comes from the metainfo, LoadDate is when the torrent was
+	// added, StartedAt and FinishedAt are reported by d.timestamp.*
+	CreationDate time.Time
+	LoadDate     time.Time
+	StartedAt    time.Time
+	FinishedAt   time.Time
+	// Custom holds d.custom1..5 under "1".."5" and d.custom=key values under their key
+	Custom map[string]string
+	// Views are the custom views the torrent was added to, d.views
+	Views    []string
+	Files    []File
+	Trackers []Tracker
+	Peers    []Peer
+}
+
+// File is the simulated state of a file within a Torrent
+type File struct {
+	Path            string
+	Size            int64
+	Priority        int
+	SizeChunks      int64
+	CompletedChunks int64
+}
+
+// Tracker is the simulated state of a tracker of a Torrent
+type Tracker struct {
+	URL string
+	// Group is the tier of the tracker within the announce-list
+	Group            int
+	Enabled          bool
+	ScrapeComplete   int
+	ScrapeIncomplete int
+	ScrapeDownloaded int
+	LastAnnounce     time.Time
+	LastSuccess      time.Time
+	SuccessCounter   int
+	FailedCounter    int
+}
+
+// Type is what t.type reports: 1 for HTTP, 2 for UDP and 3 for DHT
+func (tr *Tracker) Type() int64 {
+	switch {
+	case strings.HasPrefix(tr.URL, "udp://"):
+		return 2
+	case strings.HasPrefix(tr.URL, "dht://"):
+		return 3
+	}
+	return 1
+}
+
+// Peer is the simulated state of a peer connected to a Torrent
+type Peer struct {
+	// ID is the hex encoded peer id, which targets the peer as "HASH:pID"
+	ID               string
+	Address          string
+	Port             int
+	ClientVersion    string
+	CompletedPercent int
+	DownRate         int64
+	UpRate           int64
+	DownTotal        int64
+	UpTotal          int64
+	Encrypted        bool
+	Incoming         bool
+	Snubbed          bool
+	Banned           bool
+}
+
+// BasePath is what d.base_path reports: the file of a single file torrent,
+// or the directory of a multi file one
+func (t *Torrent) BasePath() string {
+	return path.Join(t.Directory, t.Name)
+}
+
+// Complete is what d.complete reports
+func (t *Torrent) Complete() bool {
+	return t.Size > 0 && t.CompletedBytes >= t.Size
+}
+
+// Active is what d.is_active reports
+func (t *Torrent) Active() bool {
+	return t.Open && t.Started
+}
+
+// SizeChunks is what d.size_chunks reports
+func (t *Torrent) SizeChunks() int64 {
+	return (t.Size + t.ChunkSize - 1) / t.ChunkSize
+}
+
+// CompletedChunks is what d.completed_chunks reports
+func (t *Torrent) CompletedChunks() int64 {
+	if t.Complete() {
+		return t.SizeChunks()
+	}
+	return t.CompletedBytes / t.ChunkSize
+}
+
+// Ratio is what d.ratio reports, in thousandths
+func (t *Torrent) Ratio() int64 {
+	if t.CompletedBytes == 0 {
+		return 0
+	}
+	return t.UpTotal * 1000 / t.CompletedBytes
+}
+
+func (t *Torrent) inView(name string) bool {
+	for _, v := range t.Views {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Torrent) start() {
+	t.Open, t.Started = true, true
+	t.StartedAt = time.Now()
+}
+
+func (t *Torrent) clone() Torrent {
+	c := *t
+	c.Files = append([]File(nil), t.Files...)
+	c.Trackers = append([]Tracker(nil), t.Trackers...)
+	c.Peers = append([]Peer(nil), t.Peers...)
+	c.Views = append([]string(nil), t.Views...)
+	c.Custom = make(map[string]string, len(t.Custom))
+	for k, v := range t.Custom {
+		c.Custom[k] = v
+	}
+	return c
+}
+
+// parseMetainfo builds a stopped Torrent out of the contents of a .torrent file
+func parseMetainfo(data []byte) (*Torrent, error) {
+	meta, err := metainfo.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	t := &Torrent{
+		Hash:         meta.InfoHash(),
+		Name:         meta.Info.Name,
+		ChunkSize:    meta.Info.PieceLength,
+		Private:      meta.Info.Private,
+		CreationDate: meta.CreationDate,
+		Priority:     2,
+		PeersMin:     100,
+		PeersMax:     200,
+		Custom:       map[string]string{},
+	}
+	for group, tier := range meta.AnnounceList {
+		for _, url := range tier {
+			t.Trackers = append(t.Trackers, Tracker{URL: url, Group: group, Enabled: true})
+		}
+	}
+	if t.Trackers == nil && meta.Announce != "" {
+		t.Trackers = []Tracker{{URL: meta.Announce, Enabled: true}}
+	}
+
+	if meta.Info.Files == nil {
+		t.Files = []File{{Path: t.Name, Size: meta.Info.Length}}
+	} else {
+		t.MultiFile = true
+		for _, f := range meta.Info.Files {
+			t.Files = append(t.Files, File{Path: f.PathString(), Size: f.Length})
+		}
+	}
+
+	var offset int64
+	for i := range t.Files {
+		f := &t.Files[i]
+		f.Priority = 1
+		first := offset / t.ChunkSize
+		last := first
+		if f.Size > 0 {
+			last = (offset + f.Size - 1) / t.ChunkSize
+		}
+		f.SizeChunks = last - first + 1
+		offset += f.Size
+	}
+	t.Size = offset
+	return t, nil
+}
@@ -0,0 +1,158 @@
+package rtorrent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// StatusCode is what a torrent is doing, derived from d.state, d.is_open,
+// d.is_active, d.hashing, d.complete and d.message
+type StatusCode int
+
+const (
+	// StatusStopped torrents aren't started
+	StatusStopped StatusCode = iota
+	// StatusQueued torrents are started but not open yet
+	StatusQueued
+	// StatusPaused torrents are started and open, but not active
+	StatusPaused
+	// StatusDownloading torrents are active and incomplete
+	StatusDownloading
+	// StatusSeeding torrents are active and complete
+	StatusSeeding
+	// StatusChecking torrents are being hash checked
+	StatusChecking
+	// StatusError torrents are started and have a message, such as a failure
+	// reason from a tracker or a failed hash check. Messages about a tracker
+	// being unreachable don't count, rTorrent retries on its own.
+	StatusError
+)
+
+func (s StatusCode) String() string {
+	switch s {
+	case StatusStopped:
+		return "stopped"
+	case StatusQueued:
+		return "queued"
+	case StatusPaused:
+		return "paused"
+	case StatusDownloading:
+		return "downloading"
+	case StatusSeeding:
+		return "seeding"
+	case StatusChecking:
+		return "checking"
+	case StatusError:
+		return "error"
+	}
+	return fmt.Sprintf("StatusCode(%d)", int(s))
+}
+
+// Status is a snapshot of the progress of a torrent
+type Status struct {
+	Code           StatusCode
+	Message        string
+	Completed      bool
+	CompletedBytes int64
+	Size           int64
+	DownRate       int
+	UpRate         int
+	Ratio          float64
+	// Progress is the completed percentage, from 0 to 100
+	Progress float64
+	// ETA is the estimated time left to complete the download at the current
+	// rate, 0 if complete or not downloading
+	ETA time.Duration
+}
+
+// statusFields are the d.* commands needed by Torrent.Status
+var statusFields = []Field{
+	FieldHash, FieldState, FieldIsOpen, FieldIsActive, FieldHashing, FieldCompleted, FieldMessage,
+	FieldSize, FieldCompletedBytes, FieldDownRate, FieldUpRate, FieldRatio,
+}
+
+// StatusCode derives what the torrent is doing from its State, IsOpen,
+// IsActive, Hashing, Completed and Message, which must have been fetched
+func (t *Torrent) StatusCode() StatusCode {
+	switch {
+	case t.Hashing != 0:
+		return StatusChecking
+	case t.State == 0:
+		return StatusStopped
+	case t.Message != "" && !isTransientMessage(t.Message):
+		return StatusError
+	case !t.IsOpen:
+		return StatusQueued
+	case !t.IsActive:
+		return StatusPaused
+	case t.Completed:
+		return StatusSeeding
+	}
+	return StatusDownloading
+}
+
+// isTransientMessage reports the tracker messages rTorrent sets when a
+// tracker can't be reached, such as "Tracker: [Timeout was reached]", as
+// opposed to those for a tracker answering with a failure reason
+func isTransientMessage(msg string) bool {
+	return strings.HasPrefix(msg, "Tracker: [") && !strings.Contains(msg, "Failure reason")
+}
+
+// Progress returns the completed percentage, from 0 to 100
+func (t *Torrent) Progress() float64 {
+	if t.Size == 0 {
+		return 0
+	}
+	return float64(t.CompletedBytes) * 100 / float64(t.Size)
+}
+
+// ETA estimates the time left to complete the download at the current rate.
+// It returns false if that can't be estimated: nothing is being downloaded.
+func (t *Torrent) ETA() (time.Duration, bool) {
+	left := t.Size - t.CompletedBytes
+	if left <= 0 {
+		return 0, true
+	}
+	if t.DownRate <= 0 {
+		return 0, false
+	}
+	return time.Duration(left/int64(t.DownRate)) * time.Second, true
+}
+
+// Status returns the status of the torrent; see StatusCode for the fields
+// that must have been fetched, GetTorrent fetches them all
+func (t *Torrent) Status() Status {
+	eta, _ := t.ETA()
+	return Status{
+		Code:           t.StatusCode(),
+		Message:        t.Message,
+		Completed:      t.Completed,
+		CompletedBytes: t.CompletedBytes,
+		Size:           t.Size,
+		DownRate:       t.DownRate,
+		UpRate:         t.UpRate,
+		Ratio:          t.Ratio,
+		Progress:       t.Progress(),
+		ETA:            eta,
+	}
+}
+
+// GetStatus returns the current status of the given torrent, fetching only
+// the d.* commands it needs
+func (r *RTorrent) GetStatus(t Torrent) (Status, error) {
+	return r.GetStatusContext(context.Background(), t)
+}
+
+// GetStatusContext is like GetStatus but honors ctx cancellation and deadlines
+func (r *RTorrent) GetStatusContext(ctx context.Context, t Torrent) (Status, error) {
+	torrents, err := r.Torrents(ViewMain).Hashes(t.Hash).Fields(statusFields...).DoContext(ctx)
+	if err != nil {
+		return Status{}, err
+	}
+	if len(torrents) == 0 {
+		return Status{}, ErrTorrentNotFound
+	}
+	return torrents[0].Status(), nil
+}
@@ -0,0 +1,50 @@
+package rtorrent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusCode(t *testing.T) {
+	for _, test := range []struct {
+		torrent Torrent
+		code    StatusCode
+	}{
+		{Torrent{}, StatusStopped},
+		{Torrent{Completed: true}, StatusStopped},
+		{Torrent{State: 1}, StatusQueued},
+		{Torrent{State: 1, IsOpen: true}, StatusPaused},
+		{Torrent{State: 1, IsOpen: true, IsActive: true}, StatusDownloading},
+		{Torrent{State: 1, IsOpen: true, IsActive: true, Completed: true}, StatusSeeding},
+		{Torrent{State: 1, IsOpen: true, IsActive: true, Hashing: 1}, StatusChecking},
+		{Torrent{State: 1, IsOpen: true, IsActive: true, Message: `Tracker: [Failure reason "torrent not registered"]`}, StatusError},
+		{Torrent{State: 1, IsOpen: true, IsActive: true, Message: "Hash check on download completion found bad chunks, consider using \"safe_sync\"."}, StatusError},
+		{Torrent{State: 1, IsOpen: true, IsActive: true, Message: "Tracker: [Timeout was reached]"}, StatusDownloading},
+		{Torrent{State: 1, IsOpen: true, IsActive: true, Completed: true, Message: "Tracker: [Couldn't resolve host name]"}, StatusSeeding},
+		{Torrent{Message: `Tracker: [Failure reason "torrent not registered"]`}, StatusStopped},
+	} {
+		require.Equal(t, test.code, test.torrent.StatusCode(), "%+v", test.torrent)
+	}
+	require.Equal(t, "seeding", StatusSeeding.String())
+	require.Equal(t, "StatusCode(42)", StatusCode(42).String())
+}
+
+func TestETA(t *testing.T) {
+	torrent := Torrent{Size: 1000, CompletedBytes: 400}
+	_, ok := torrent.ETA()
+	require.False(t, ok)
+	require.Equal(t, 40.0, torrent.Progress())
+
+	torrent.DownRate = 100
+	eta, ok := torrent.ETA()
+	require.True(t, ok)
+	require.Equal(t, 6*time.Second, eta)
+
+	torrent.CompletedBytes = 1000
+	eta, ok = torrent.ETA()
+	require.True(t, ok)
+	require.Equal(t, time.Duration(0), eta)
+	require.Equal(t, 0.0, (&Torrent{}).Progress())
+}
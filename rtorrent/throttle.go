@@ -0,0 +1,272 @@
+package rtorrent
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// Rate is a transfer rate in bytes per second. As a limit, 0 means unlimited.
+type Rate int64
+
+// Rate units
+const (
+	BytesPerSecond Rate = 1
+	KiBPerSecond        = 1024 * BytesPerSecond
+	MiBPerSecond        = 1024 * KiBPerSecond
+)
+
+func (r Rate) String() string {
+	switch {
+	case r >= MiBPerSecond:
+		return strconv.FormatFloat(float64(r)/float64(MiBPerSecond), 'f', -1, 64) + " MiB/s"
+	case r >= KiBPerSecond:
+		return strconv.FormatFloat(float64(r)/float64(KiBPerSecond), 'f', -1, 64) + " KiB/s"
+	}
+	return fmt.Sprintf("%d B/s", int64(r))
+}
+
+// DownLimit returns the global download rate limit, 0 if unlimited
+func (r *RTorrent) DownLimit() (Rate, error) {
+	return r.DownLimitContext(context.Background())
+}
+
+// DownLimitContext is like DownLimit but honors ctx cancellation and deadlines
+func (r *RTorrent) DownLimitContext(ctx context.Context) (Rate, error) {
+	rate, err := r.getInt(ctx, "throttle.global_down.max_rate")
+	return Rate(rate), err
+}
+
+// SetDownLimit sets the global download rate limit, 0 for unlimited
+func (r *RTorrent) SetDownLimit(rate Rate) error {
+	return r.SetDownLimitContext(context.Background(), rate)
+}
+
+// SetDownLimitContext is like SetDownLimit but honors ctx cancellation and deadlines
+func (r *RTorrent) SetDownLimitContext(ctx context.Context, rate Rate) error {
+	return r.setInt(ctx, "throttle.global_down.max_rate.set", int64(rate))
+}
+
+// UpLimit returns the global upload rate limit, 0 if unlimited
+func (r *RTorrent) UpLimit() (Rate, error) {
+	return r.UpLimitContext(context.Background())
+}
+
+// UpLimitContext is like UpLimit but honors ctx cancellation and deadlines
+func (r *RTorrent) UpLimitContext(ctx context.Context) (Rate, error) {
+	rate, err := r.getInt(ctx, "throttle.global_up.max_rate")
+	return Rate(rate), err
+}
+
+// SetUpLimit sets the global upload rate limit, 0 for unlimited
+func (r *RTorrent) SetUpLimit(rate Rate) error {
+	return r.SetUpLimitContext(context.Background(), rate)
+}
+
+// SetUpLimitContext is like SetUpLimit but honors ctx cancellation and deadlines
+func (r *RTorrent) SetUpLimitContext(ctx context.Context, rate Rate) error {
+	return r.setInt(ctx, "throttle.global_up.max_rate.set", int64(rate))
+}
+
+// ThrottleDownLimit returns the download rate limit of the throttle group
+// `name`, 0 if it is unthrottled. It fails with an error matching
+// ErrInvalidArgument if the group isn't defined.
+func (r *RTorrent) ThrottleDownLimit(name string) (Rate, error) {
+	return r.ThrottleDownLimitContext(context.Background(), name)
+}
+
+// ThrottleDownLimitContext is like ThrottleDownLimit but honors ctx
+// cancellation and deadlines
+func (r *RTorrent) ThrottleDownLimitContext(ctx context.Context, name string) (Rate, error) {
+	return r.throttleLimit(ctx, "throttle.down.max", name)
+}
+
+// SetThrottleDownLimit defines the throttle group `name`, or changes its
+// download rate limit, 0 for unlimited. rTorrent handles throttles in KiB/s:
+// `rate` is rounded down to a multiple of KiBPerSecond, but rates below 1
+// KiB/s are rounded up to it rather than lifting the limit.
+func (r *RTorrent) SetThrottleDownLimit(name string, rate Rate) error {
+	return r.SetThrottleDownLimitContext(context.Background(), name, rate)
+}
+
+// SetThrottleDownLimitContext is like SetThrottleDownLimit but honors ctx
+// cancellation and deadlines
+func (r *RTorrent) SetThrottleDownLimitContext(ctx context.Context, name string, rate Rate) error {
+	return r.setThrottle(ctx, "throttle.down", name, rate)
+}
+
+// ThrottleUpLimit returns the upload rate limit of the throttle group `name`,
+// see ThrottleDownLimit
+func (r *RTorrent) ThrottleUpLimit(name string) (Rate, error) {
+	return r.ThrottleUpLimitContext(context.Background(), name)
+}
+
+// ThrottleUpLimitContext is like ThrottleUpLimit but honors ctx cancellation
+// and deadlines
+func (r *RTorrent) ThrottleUpLimitContext(ctx context.Context, name string) (Rate, error) {
+	return r.throttleLimit(ctx, "throttle.up.max", name)
+}
+
+// SetThrottleUpLimit defines the throttle group `name`, or changes its upload
+// rate limit, see SetThrottleDownLimit
+func (r *RTorrent) SetThrottleUpLimit(name string, rate Rate) error {
+	return r.SetThrottleUpLimitContext(context.Background(), name, rate)
+}
+
+// SetThrottleUpLimitContext is like SetThrottleUpLimit but honors ctx
+// cancellation and deadlines
+func (r *RTorrent) SetThrottleUpLimitContext(ctx context.Context, name string, rate Rate) error {
+	return r.setThrottle(ctx, "throttle.up", name, rate)
+}
+
+func (r *RTorrent) throttleLimit(ctx context.Context, method string, name string) (Rate, error) {
+	var rate Rate
+	if err := r.call(ctx, &rate, method, "", name); err != nil {
+		return 0, err
+	}
+	// rTorrent reports undefined throttle groups as -1
+	if rate < 0 {
+		return 0, errors.Wrapf(ErrInvalidArgument, "throttle group %q isn't defined", name)
+	}
+	return rate, nil
+}
+
+// throttleKiB converts `rate` to KiB/s, rounding down all but the non-zero
+// rates below 1 KiB/s, which rTorrent would take as unlimited
+func throttleKiB(rate Rate) int64 {
+	if rate > 0 && rate < KiBPerSecond {
+		return 1
+	}
+	return int64(rate / KiBPerSecond)
+}
+
+func (r *RTorrent) setThrottle(ctx context.Context, method string, name string, rate Rate) error {
+	_, err := r.transport.Call(ctx, method, "", name, strconv.FormatInt(throttleKiB(rate), 10))
+	if err != nil {
+		return wrapCallError(method, err)
+	}
+	return nil
+}
+
+// SetThrottle assigns the torrent to the throttle group `name`, or to the
+// global throttle if empty. rTorrent only lets stopped torrents change throttle.
+func (r *RTorrent) SetThrottle(t Torrent, name string) error {
+	return r.SetThrottleContext(context.Background(), t, name)
+}
+
+// SetThrottleContext is like SetThrottle but honors ctx cancellation and deadlines
+func (r *RTorrent) SetThrottleContext(ctx context.Context, t Torrent, name string) error {
+	_, err := r.transport.Call(ctx, "d.throttle_name.set", t.Hash, name)
+	if err != nil {
+		return wrapCallError("d.throttle_name.set", err)
+	}
+	return nil
+}
+
+// MaxPeers returns the maximum number of peers of downloading torrents
+func (r *RTorrent) MaxPeers() (int, error) {
+	return r.MaxPeersContext(context.Background())
+}
+
+// MaxPeersContext is like MaxPeers but honors ctx cancellation and deadlines
+func (r *RTorrent) MaxPeersContext(ctx context.Context) (int, error) {
+	n, err := r.getInt(ctx, "throttle.max_peers.normal")
+	return int(n), err
+}
+
+// SetMaxPeers sets the maximum number of peers of downloading torrents
+func (r *RTorrent) SetMaxPeers(n int) error {
+	return r.SetMaxPeersContext(context.Background(), n)
+}
+
+// SetMaxPeersContext is like SetMaxPeers but honors ctx cancellation and deadlines
+func (r *RTorrent) SetMaxPeersContext(ctx context.Context, n int) error {
+	return r.setInt(ctx, "throttle.max_peers.normal.set", int64(n))
+}
+
+// MaxPeersSeed returns the maximum number of peers of seeding torrents, -1
+// if the same as MaxPeers
+func (r *RTorrent) MaxPeersSeed() (int, error) {
+	return r.MaxPeersSeedContext(context.Background())
+}
+
+// MaxPeersSeedContext is like MaxPeersSeed but honors ctx cancellation and deadlines
+func (r *RTorrent) MaxPeersSeedContext(ctx context.Context) (int, error) {
+	n, err := r.getInt(ctx, "throttle.max_peers.seed")
+	return int(n), err
+}
+
+// SetMaxPeersSeed sets the maximum number of peers of seeding torrents, -1
+// to use MaxPeers
+func (r *RTorrent) SetMaxPeersSeed(n int) error {
+	return r.SetMaxPeersSeedContext(context.Background(), n)
+}
+
+// SetMaxPeersSeedContext is like SetMaxPeersSeed but honors ctx cancellation and deadlines
+func (r *RTorrent) SetMaxPeersSeedContext(ctx context.Context, n int) error {
+	return r.setInt(ctx, "throttle.max_peers.seed.set", int64(n))
+}
+
+// MaxUploads returns the maximum number of peers uploaded to per torrent
+func (r *RTorrent) MaxUploads() (int, error) {
+	return r.MaxUploadsContext(context.Background())
+}
+
+// MaxUploadsContext is like MaxUploads but honors ctx cancellation and deadlines
+func (r *RTorrent) MaxUploadsContext(ctx context.Context) (int, error) {
+	n, err := r.getInt(ctx, "throttle.max_uploads")
+	return int(n), err
+}
+
+// SetMaxUploads sets the maximum number of peers uploaded to per torrent
+func (r *RTorrent) SetMaxUploads(n int) error {
+	return r.SetMaxUploadsContext(context.Background(), n)
+}
+
+// SetMaxUploadsContext is like SetMaxUploads but honors ctx cancellation and deadlines
+func (r *RTorrent) SetMaxUploadsContext(ctx context.Context, n int) error {
+	return r.setInt(ctx, "throttle.max_uploads.set", int64(n))
+}
+
+// MaxUploadsGlobal returns the maximum number of peers uploaded to across
+// all torrents, 0 if unlimited
+func (r *RTorrent) MaxUploadsGlobal() (int, error) {
+	return r.MaxUploadsGlobalContext(context.Background())
+}
+
+// MaxUploadsGlobalContext is like MaxUploadsGlobal but honors ctx
+// cancellation and deadlines
+func (r *RTorrent) MaxUploadsGlobalContext(ctx context.Context) (int, error) {
+	n, err := r.getInt(ctx, "throttle.max_uploads.global")
+	return int(n), err
+}
+
+// SetMaxUploadsGlobal sets the maximum number of peers uploaded to across
+// all torrents, 0 for unlimited
+func (r *RTorrent) SetMaxUploadsGlobal(n int) error {
+	return r.SetMaxUploadsGlobalContext(context.Background(), n)
+}
+
+// SetMaxUploadsGlobalContext is like SetMaxUploadsGlobal but honors ctx
+// cancellation and deadlines
+func (r *RTorrent) SetMaxUploadsGlobalContext(ctx context.Context, n int) error {
+	return r.setInt(ctx, "throttle.max_uploads.global.set", int64(n))
+}
+
+// getInt calls a global getter returning an integer
+func (r *RTorrent) getInt(ctx context.Context, method string) (int64, error) {
+	var n int64
+	err := r.call(ctx, &n, method)
+	return n, err
+}
+
+// setInt calls a global setter, which takes an empty target first
+func (r *RTorrent) setInt(ctx context.Context, method string, value int64) error {
+	_, err := r.transport.Call(ctx, method, "", value)
+	if err != nil {
+		return wrapCallError(method, err)
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package rtorrent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateString(t *testing.T) {
+	require.Equal(t, "0 B/s", Rate(0).String())
+	require.Equal(t, "512 B/s", Rate(512).String())
+	require.Equal(t, "1.5 KiB/s", (KiBPerSecond * 3 / 2).String())
+	require.Equal(t, "2 MiB/s", (2 * MiBPerSecond).String())
+}
+
+func TestThrottleKiB(t *testing.T) {
+	require.Equal(t, int64(0), throttleKiB(0))
+	require.Equal(t, int64(1), throttleKiB(1))
+	require.Equal(t, int64(1), throttleKiB(KiBPerSecond-1))
+	require.Equal(t, int64(1), throttleKiB(KiBPerSecond*3/2))
+	require.Equal(t, int64(2048), throttleKiB(2*MiBPerSecond))
+}
@@ -0,0 +1,134 @@
+package rtorrent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tab1293/go-rtorrent/xmlrpc"
+)
+
+// TrackerType is the protocol of a tracker, t.type
+type TrackerType int
+
+const (
+	// TrackerHTTP trackers announce over HTTP(S)
+	TrackerHTTP TrackerType = 1
+	// TrackerUDP trackers announce over UDP
+	TrackerUDP TrackerType = 2
+	// TrackerDHT is the DHT, listed as a tracker of public torrents
+	TrackerDHT TrackerType = 3
+)
+
+func (t TrackerType) String() string {
+	switch t {
+	case TrackerHTTP:
+		return "http"
+	case TrackerUDP:
+		return "udp"
+	case TrackerDHT:
+		return "dht"
+	}
+	return fmt.Sprintf("TrackerType(%d)", int(t))
+}
+
+// Tracker represents a tracker of a torrent.
+//
+// Tracker has no message: rTorrent keeps no message per tracker, and has no
+// t.* command to fetch one. The last tracker error, such as a failure reason,
+// is reported for the whole torrent in Torrent.Message, without saying which
+// tracker it came from.
+type Tracker struct {
+	URL string `xmlrpc:"t.url="`
+	// Group is the tier of the tracker
+	Group            int         `xmlrpc:"t.group="`
+	Type             TrackerType `xmlrpc:"t.type="`
+	Enabled          bool        `xmlrpc:"t.is_enabled="`
+	ScrapeComplete   int         `xmlrpc:"t.scrape_complete="`
+	ScrapeIncomplete int         `xmlrpc:"t.scrape_incomplete="`
+	ScrapeDownloaded int         `xmlrpc:"t.scrape_downloaded="`
+	LastAnnounce     time.Time   `xmlrpc:"t.activity_time_last="`
+	LastSuccess      time.Time   `xmlrpc:"t.success_time_last="`
+	SuccessCounter   int         `xmlrpc:"t.success_counter="`
+	FailedCounter    int         `xmlrpc:"t.failed_counter="`
+	// Index is the position of the tracker within the torrent, used to
+	// target it
+	Index int `xmlrpc:"-"`
+}
+
+// trackerFields are the t.* commands fetched by GetTrackers, every field of `Tracker`
+var trackerFields = xmlrpc.FieldNames(Tracker{})
+
+// GetTrackers returns the trackers of the torrent
+func (r *RTorrent) GetTrackers(t Torrent) ([]Tracker, error) {
+	return r.GetTrackersContext(context.Background(), t)
+}
+
+// GetTrackersContext is like GetTrackers but honors ctx cancellation and deadlines
+func (r *RTorrent) GetTrackersContext(ctx context.Context, t Torrent) ([]Tracker, error) {
+	var trackers []Tracker
+	if err := r.multicall(ctx, &trackers, "t.multicall", []interface{}{t.Hash, ""}, trackerFields); err != nil {
+		return nil, err
+	}
+	for i := range trackers {
+		trackers[i].Index = i
+	}
+	return trackers, nil
+}
+
+// AddTracker adds a tracker to the first tier of the torrent
+func (r *RTorrent) AddTracker(t Torrent, url string) error {
+	return r.AddTrackerContext(context.Background(), t, url)
+}
+
+// AddTrackerContext is like AddTracker but honors ctx cancellation and deadlines
+func (r *RTorrent) AddTrackerContext(ctx context.Context, t Torrent, url string) error {
+	_, err := r.transport.Call(ctx, "d.tracker.insert", t.Hash, "0", url)
+	if err != nil {
+		return wrapCallError("d.tracker.insert", err)
+	}
+	return nil
+}
+
+// EnableTracker enables the tracker at index i of the torrent
+func (r *RTorrent) EnableTracker(t Torrent, i int) error {
+	return r.EnableTrackerContext(context.Background(), t, i)
+}
+
+// EnableTrackerContext is like EnableTracker but honors ctx cancellation and deadlines
+func (r *RTorrent) EnableTrackerContext(ctx context.Context, t Torrent, i int) error {
+	return r.setTrackerEnabled(ctx, t, i, 1)
+}
+
+// DisableTracker disables the tracker at index i of the torrent, which is no
+// longer announced to
+func (r *RTorrent) DisableTracker(t Torrent, i int) error {
+	return r.DisableTrackerContext(context.Background(), t, i)
+}
+
+// DisableTrackerContext is like DisableTracker but honors ctx cancellation and deadlines
+func (r *RTorrent) DisableTrackerContext(ctx context.Context, t Torrent, i int) error {
+	return r.setTrackerEnabled(ctx, t, i, 0)
+}
+
+func (r *RTorrent) setTrackerEnabled(ctx context.Context, t Torrent, i int, enabled int) error {
+	_, err := r.transport.Call(ctx, "t.is_enabled.set", fmt.Sprintf("%s:t%d", t.Hash, i), enabled)
+	if err != nil {
+		return wrapCallError("t.is_enabled.set", err)
+	}
+	return nil
+}
+
+// AnnounceNow announces the torrent to its enabled trackers right away
+func (r *RTorrent) AnnounceNow(t Torrent) error {
+	return r.AnnounceNowContext(context.Background(), t)
+}
+
+// AnnounceNowContext is like AnnounceNow but honors ctx cancellation and deadlines
+func (r *RTorrent) AnnounceNowContext(ctx context.Context, t Torrent) error {
+	_, err := r.transport.Call(ctx, "d.tracker_announce", t.Hash)
+	if err != nil {
+		return wrapCallError("d.tracker_announce", err)
+	}
+	return nil
+}
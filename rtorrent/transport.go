@@ -0,0 +1,107 @@
+package rtorrent
+
+import (
+	"context"
+
+	"github.com/tab1293/go-rtorrent/jsonrpc"
+	"github.com/tab1293/go-rtorrent/xmlrpc"
+)
+
+// Transport carries commands to an rTorrent instance over some RPC protocol.
+// Results must use the types produced by the xmlrpc package, see xmlrpc.Decode.
+type Transport interface {
+	// Call calls `method` with the given args and returns its result
+	Call(ctx context.Context, method string, args ...interface{}) (interface{}, error)
+	// Multicall calls several methods in a single round trip. A failing call
+	// is reported through its CallResult.Err rather than the returned error,
+	// which is reserved for communication errors.
+	Multicall(ctx context.Context, calls []Call) ([]CallResult, error)
+}
+
+// Call is a single method call within a Transport.Multicall
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+// CallResult is the outcome of a single Call within a Transport.Multicall
+type CallResult struct {
+	// Value is the value returned by the call, nil if the call failed
+	Value interface{}
+	// Err is set if the call failed: an *xmlrpc.Fault or a *jsonrpc.Error
+	Err error
+}
+
+// NewXMLRPCTransport returns a Transport speaking XML-RPC through `client`,
+// batching calls with system.multicall
+func NewXMLRPCTransport(client *xmlrpc.Client) Transport {
+	return &xmlrpcTransport{client: client}
+}
+
+type xmlrpcTransport struct {
+	client *xmlrpc.Client
+}
+
+func (t *xmlrpcTransport) Call(ctx context.Context, method string, args ...interface{}) (interface{}, error) {
+	result, err := t.client.CallContext(ctx, method, args...)
+	if err != nil {
+		return nil, err
+	}
+	return singleResult(method, result)
+}
+
+func (t *xmlrpcTransport) Multicall(ctx context.Context, calls []Call) ([]CallResult, error) {
+	batch := t.client.NewBatch()
+	for _, c := range calls {
+		batch.Add(c.Method, c.Args...)
+	}
+	results, err := batch.SendContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]CallResult, len(results))
+	for i, r := range results {
+		out[i].Value = r.Value
+		if r.Fault != nil {
+			out[i].Err = r.Fault
+		}
+	}
+	return out, nil
+}
+
+// NewJSONRPCTransport returns a Transport speaking JSON-RPC 2.0 through
+// `client`, batching calls with JSON-RPC batch requests
+func NewJSONRPCTransport(client *jsonrpc.Client) Transport {
+	return &jsonrpcTransport{client: client}
+}
+
+type jsonrpcTransport struct {
+	client *jsonrpc.Client
+}
+
+func (t *jsonrpcTransport) Call(ctx context.Context, method string, args ...interface{}) (interface{}, error) {
+	result, err := t.client.CallContext(ctx, method, args...)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (t *jsonrpcTransport) Multicall(ctx context.Context, calls []Call) ([]CallResult, error) {
+	batch := t.client.NewBatch()
+	for _, c := range calls {
+		batch.Add(c.Method, c.Args...)
+	}
+	results, err := batch.SendContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]CallResult, len(results))
+	for i, r := range results {
+		out[i].Value = r.Value
+		if r.Error != nil {
+			out[i].Err = r.Error
+		}
+	}
+	return out, nil
+}
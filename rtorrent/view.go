@@ -0,0 +1,83 @@
+package rtorrent
+
+import (
+	"context"
+	"fmt"
+)
+
+// ListViews returns the names of every view, built-in and custom
+func (r *RTorrent) ListViews() ([]View, error) {
+	return r.ListViewsContext(context.Background())
+}
+
+// ListViewsContext is like ListViews but honors ctx cancellation and deadlines
+func (r *RTorrent) ListViewsContext(ctx context.Context) ([]View, error) {
+	var views []View
+	err := r.call(ctx, &views, "view.list")
+	return views, err
+}
+
+// CreateView adds the custom view `name`, holding the torrents matching
+// `filter`, an rTorrent filter expression such as "d.complete=" or
+// "and={d.is_active=,not={d.complete=}}". With an empty filter, the view holds
+// the torrents added to it with AddToView. Either way, it can be queried like
+// any other view, e.g. with GetTorrents. It fails with an error matching
+// ErrInvalidArgument if the view exists already.
+func (r *RTorrent) CreateView(name View, filter string) error {
+	return r.CreateViewContext(context.Background(), name, filter)
+}
+
+// CreateViewContext is like CreateView but honors ctx cancellation and deadlines
+func (r *RTorrent) CreateViewContext(ctx context.Context, name View, filter string) error {
+	if filter == "" {
+		filter = fmt.Sprintf("d.views.has=%s", name)
+	}
+	return r.callAll(ctx,
+		Call{Method: "view.add", Args: []interface{}{"", string(name)}},
+		Call{Method: "view.filter", Args: []interface{}{"", string(name), filter}},
+	)
+}
+
+// ClearView empties the custom view `name`, its filter being replaced by one
+// matching no torrent. rTorrent can't remove a view: it is still listed by
+// ListViews until rTorrent restarts, and CreateView fails for its name until
+// then.
+func (r *RTorrent) ClearView(name View) error {
+	return r.ClearViewContext(context.Background(), name)
+}
+
+// ClearViewContext is like ClearView but honors ctx cancellation and deadlines
+func (r *RTorrent) ClearViewContext(ctx context.Context, name View) error {
+	_, err := r.transport.Call(ctx, "view.filter", "", string(name), "false=")
+	if err != nil {
+		return wrapCallError("view.filter", err)
+	}
+	return nil
+}
+
+// AddToView adds the torrent to the custom view `name`, created with an empty
+// filter
+func (r *RTorrent) AddToView(t Torrent, name View) error {
+	return r.AddToViewContext(context.Background(), t, name)
+}
+
+// AddToViewContext is like AddToView but honors ctx cancellation and deadlines
+func (r *RTorrent) AddToViewContext(ctx context.Context, t Torrent, name View) error {
+	return r.callAll(ctx,
+		Call{Method: "d.views.push_back_unique", Args: []interface{}{t.Hash, string(name)}},
+		Call{Method: "view.set_visible", Args: []interface{}{t.Hash, string(name)}},
+	)
+}
+
+// RemoveFromView removes the torrent from the custom view `name`
+func (r *RTorrent) RemoveFromView(t Torrent, name View) error {
+	return r.RemoveFromViewContext(context.Background(), t, name)
+}
+
+// RemoveFromViewContext is like RemoveFromView but honors ctx cancellation and deadlines
+func (r *RTorrent) RemoveFromViewContext(ctx context.Context, t Torrent, name View) error {
+	return r.callAll(ctx,
+		Call{Method: "d.views.remove", Args: []interface{}{t.Hash, string(name)}},
+		Call{Method: "view.set_not_visible", Args: []interface{}{t.Hash, string(name)}},
+	)
+}
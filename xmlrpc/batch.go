@@ -0,0 +1,111 @@
+package xmlrpc
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Batch queues method calls and sends them as a single system.multicall,
+// saving one round trip per call
+type Batch struct {
+	client *Client
+	calls  []batchCall
+}
+
+type batchCall struct {
+	name string
+	args []interface{}
+}
+
+// BatchResult is the outcome of a single call within a Batch
+type BatchResult struct {
+	// Value is the value returned by the call, nil if the call failed
+	Value interface{}
+	// Fault is set if the call failed
+	Fault *Fault
+}
+
+// NewBatch returns a new, empty Batch sent through this Client
+func (c *Client) NewBatch() *Batch {
+	return &Batch{client: c}
+}
+
+// Add queues the method with "name" with the given args
+func (b *Batch) Add(name string, args ...interface{}) *Batch {
+	b.calls = append(b.calls, batchCall{name: name, args: args})
+	return b
+}
+
+// Len returns the number of queued calls
+func (b *Batch) Len() int {
+	return len(b.calls)
+}
+
+// Send sends all queued calls as one system.multicall
+// Returns one result per queued call, in order; a failing call is reported
+// through its BatchResult.Fault rather than the returned error, which is
+// reserved for communication errors
+func (b *Batch) Send() ([]BatchResult, error) {
+	return b.SendContext(context.Background())
+}
+
+// SendContext is like Send but honors ctx cancellation and deadlines
+func (b *Batch) SendContext(ctx context.Context) ([]BatchResult, error) {
+	if len(b.calls) == 0 {
+		return []BatchResult{}, nil
+	}
+
+	calls := make([]interface{}, 0, len(b.calls))
+	for _, c := range b.calls {
+		params := c.args
+		if params == nil {
+			params = []interface{}{}
+		}
+		calls = append(calls, map[string]interface{}{
+			"methodName": c.name,
+			"params":     params,
+		})
+	}
+
+	result, err := b.client.CallContext(ctx, "system.multicall", calls)
+	if err != nil {
+		return nil, err
+	}
+	return parseMulticall(result, len(b.calls))
+}
+
+// parseMulticall splits a system.multicall response into one BatchResult
+// per call: a one element array on success, a fault struct on failure
+func parseMulticall(result interface{}, n int) ([]BatchResult, error) {
+	if params, ok := result.([]interface{}); ok && len(params) == 1 {
+		result = params[0]
+	}
+	items, ok := result.([]interface{})
+	if !ok {
+		return nil, errors.Errorf("system.multicall result isn't an array: %v", result)
+	}
+	if len(items) != n {
+		return nil, errors.Errorf("system.multicall returned %d results for %d calls", len(items), n)
+	}
+
+	results := make([]BatchResult, len(items))
+	for i, item := range items {
+		switch v := item.(type) {
+		case []interface{}:
+			if len(v) != 1 {
+				return nil, errors.Errorf("system.multicall result %d has %d values", i, len(v))
+			}
+			results[i].Value = v[0]
+		case map[string]interface{}:
+			fault, err := faultFromMap(v)
+			if err != nil {
+				return nil, errors.Wrapf(err, "system.multicall result %d", i)
+			}
+			results[i].Fault = fault
+		default:
+			return nil, errors.Errorf("system.multicall result %d is unexpected: %v", i, item)
+		}
+	}
+	return results, nil
+}
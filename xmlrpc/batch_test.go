@@ -0,0 +1,65 @@
+package xmlrpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatch(t *testing.T) {
+	var received []interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, params, _, err := Unmarshal(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, "system.multicall", name)
+		received = params[0].([]interface{})
+
+		var results []interface{}
+		for _, c := range received {
+			call := c.(map[string]interface{})
+			switch call["methodName"] {
+			case "d.start":
+				results = append(results, []interface{}{0})
+			default:
+				results = append(results, map[string]interface{}{
+					"faultCode":   -506,
+					"faultString": "Method not defined",
+				})
+			}
+		}
+		require.NoError(t, Marshal(w, "", results))
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL, false)
+
+	t.Run("empty", func(t *testing.T) {
+		results, err := client.NewBatch().Send()
+		require.NoError(t, err)
+		require.Empty(t, results)
+	})
+
+	t.Run("results and faults", func(t *testing.T) {
+		b := client.NewBatch().
+			Add("d.start", "HASH1").
+			Add("d.bogus").
+			Add("d.start", "HASH2")
+		require.Equal(t, 3, b.Len())
+
+		results, err := b.Send()
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		require.Len(t, received, 3)
+		require.Equal(t, "d.start", received[0].(map[string]interface{})["methodName"])
+		require.Equal(t, []interface{}{"HASH1"}, received[0].(map[string]interface{})["params"])
+		require.Equal(t, []interface{}{}, received[1].(map[string]interface{})["params"])
+
+		require.Nil(t, results[0].Fault)
+		require.Equal(t, int64(0), results[0].Value)
+		require.Equal(t, &Fault{Code: -506, Message: "Method not defined"}, results[1].Fault)
+		require.Nil(t, results[1].Value)
+		require.Nil(t, results[2].Fault)
+	})
+}
@@ -2,21 +2,48 @@ package xmlrpc
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
+	"io"
 	"net/http"
 
 	"github.com/pkg/errors"
 )
 
+// TransportError is returned when a request couldn't be carried to the
+// server and back: connection failures, timeouts, non-2xx HTTP responses.
+// It tells those apart from faults, which are returned as a *Fault.
+type TransportError struct {
+	// StatusCode is the HTTP status of the response, 0 if there was none
+	StatusCode int
+	// Err is the underlying error
+	Err error
+}
+
+func (e *TransportError) Error() string {
+	return "POST failed: " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error, e.g. context.DeadlineExceeded
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
 // Client implements a basic XMLRPC client
 type Client struct {
 	addr       string
 	httpClient *http.Client
+	stream     bool
 }
 
 // NewClient returns a new instance of Client
 // Pass in a true value for `insecure` to turn off certificate verification
+// An "scgi://" address talks SCGI directly to rTorrent, see SCGITransport
 func NewClient(addr string, insecure bool) *Client {
+	if IsSCGI(addr) {
+		return NewClientWithHTTPClient(addr, &http.Client{Transport: NewSCGITransport(addr)})
+	}
+
 	transport := &http.Transport{}
 	if insecure {
 		transport = &http.Transport{
@@ -41,22 +68,67 @@ func NewClientWithHTTPClient(addr string, client *http.Client) *Client {
 	}
 }
 
+// SetStreaming turns streaming of request bodies on or off.
+// When on, requests are marshalled straight into the HTTP request body
+// through an io.Pipe and sent with chunked transfer encoding, instead of
+// being buffered in full first; this keeps memory flat for very large
+// requests such as big multicalls.
+// The server (or the web server in front of rTorrent) must accept chunked
+// requests. SCGI always needs the full length up front, so requests sent
+// through SCGITransport are buffered regardless.
+// Call this before the Client is used.
+func (c *Client) SetStreaming(stream bool) {
+	c.stream = stream
+}
+
 // Call calls the method with "name" with the given args
 // Returns the result, and an error for communication errors
 func (c *Client) Call(name string, args ...interface{}) (interface{}, error) {
-	req := bytes.NewBuffer(nil)
-	if err := Marshal(req, name, args...); err != nil {
-		return nil, errors.Wrap(err, "failed to marshal request")
+	return c.CallContext(context.Background(), name, args...)
+}
+
+// CallContext calls the method with "name" with the given args.
+// The request is aborted as soon as ctx is cancelled or its deadline passes.
+// Returns the result; the *Fault if the server answered with one, a
+// *TransportError for communication errors, or another error for an
+// unparseable response
+func (c *Client) CallContext(ctx context.Context, name string, args ...interface{}) (interface{}, error) {
+	var body io.Reader
+	if c.stream {
+		pr, pw := io.Pipe()
+		go func() {
+			// the transport closes pr once it's done with the body,
+			// which unblocks this goroutine if the request fails early
+			pw.CloseWithError(Marshal(pw, name, args...))
+		}()
+		body = pr
+	} else {
+		buf := bytes.NewBuffer(nil)
+		if err := Marshal(buf, name, args...); err != nil {
+			return nil, errors.Wrap(err, "failed to marshal request")
+		}
+		body = buf
 	}
-	resp, err := c.httpClient.Post(c.addr, "text/xml", req)
+	req, err := http.NewRequest(http.MethodPost, c.addr, body)
 	if err != nil {
-		return nil, errors.Wrap(err, "POST failed")
+		if closer, ok := body.(io.Closer); ok {
+			closer.Close()
+		}
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("Content-Type", "text/xml")
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, &TransportError{Err: err}
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &TransportError{StatusCode: resp.StatusCode, Err: errors.New(resp.Status)}
+	}
 
 	_, val, fault, err := Unmarshal(resp.Body)
 	if fault != nil {
-		err = errors.Errorf("Error: %v: %v", err, fault)
+		return nil, fault
 	}
 	return val, err
 }
@@ -1,3 +1,118 @@
 package xmlrpc
 
-// TODO: add tests
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallContext(t *testing.T) {
+	t.Run("http", func(t *testing.T) {
+		release := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer srv.Close()
+		defer close(release)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := NewClient(srv.URL, false).CallContext(ctx, "system.hostname")
+		require.True(t, errors.Is(err, context.DeadlineExceeded), "got %v", err)
+	})
+
+	t.Run("scgi", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer l.Close()
+		go func() {
+			// accept, but never answer
+			conn, err := l.Accept()
+			if err == nil {
+				defer conn.Close()
+				<-time.After(time.Second)
+			}
+		}()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-time.After(50 * time.Millisecond)
+			cancel()
+		}()
+		start := time.Now()
+		_, err = NewClient("scgi://"+l.Addr().String(), false).CallContext(ctx, "system.hostname")
+		require.Error(t, err)
+		require.True(t, time.Since(start) < time.Second, "call was not cancelled")
+	})
+}
+
+func TestStreaming(t *testing.T) {
+	// the handler reports what it received, to be checked on the test goroutine
+	type request struct {
+		contentLength int64
+		name          string
+		params        []interface{}
+		err           error
+	}
+	requests := make(chan request, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, params, _, err := Unmarshal(r.Body)
+		requests <- request{r.ContentLength, name, params, err}
+		if err == nil && len(params) == 2 {
+			if data, ok := params[1].([]byte); ok {
+				Marshal(w, "", len(data))
+			}
+		}
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL, false)
+	client.SetStreaming(true)
+	data := make([]byte, 1<<20)
+	result, err := client.Call("load.raw", "", data)
+	req := <-requests
+	require.NoError(t, req.err)
+	require.Equal(t, "load.raw", req.name)
+	require.Equal(t, []interface{}{"", data}, req.params)
+	require.Equal(t, int64(-1), req.contentLength, "expected a chunked request")
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{int64(len(data))}, result)
+
+	t.Run("marshal error", func(t *testing.T) {
+		_, err := client.Call("load.raw", make(chan int))
+		require.Error(t, err)
+	})
+}
+
+func TestCallErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		Marshal(w, "", &Fault{Code: -501, Message: "Could not find info-hash."})
+	}))
+	defer srv.Close()
+
+	_, err := NewClient(srv.URL, false).Call("d.name", "HASH")
+	var fault *Fault
+	require.True(t, errors.As(err, &fault), "got %T: %v", err, err)
+	require.Equal(t, &Fault{Code: -501, Message: "Could not find info-hash."}, fault)
+
+	_, err = NewClient(srv.URL+"/missing", false).Call("d.name", "HASH")
+	var transportErr *TransportError
+	require.True(t, errors.As(err, &transportErr), "got %T: %v", err, err)
+	require.Equal(t, http.StatusNotFound, transportErr.StatusCode)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = NewClient(srv.URL, false).CallContext(ctx, "d.name", "HASH")
+	require.True(t, errors.As(err, &transportErr), "got %T: %v", err, err)
+	require.True(t, errors.Is(err, context.Canceled))
+}
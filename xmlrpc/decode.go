@@ -0,0 +1,342 @@
+package xmlrpc
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DecodeError is returned by Decode when a value can't be stored into the
+// destination type
+type DecodeError struct {
+	// Path locates the offending value within the destination, e.g. "[3].Size"
+	Path string
+	// Field is the XML-RPC member name of the innermost struct field being
+	// decoded, e.g. "d.size_bytes=", empty outside of structs
+	Field string
+	// Value is the value that couldn't be decoded
+	Value interface{}
+	// Type is the Go type it should have been decoded into
+	Type reflect.Type
+}
+
+func (e *DecodeError) Error() string {
+	msg := fmt.Sprintf("xmlrpc: cannot decode %T %#v into %v", e.Value, e.Value, e.Type)
+	if e.Path != "" {
+		msg += " at " + e.Path
+	}
+	if e.Field != "" {
+		msg += " (" + e.Field + ")"
+	}
+	return msg
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Decode stores `src`, a value as returned by Unmarshal or Client.Call, into
+// the value pointed to by `dst`.
+//
+// Structs are decoded from XML-RPC structs by member name, and from arrays by
+// position. Member names are taken from the `xmlrpc:"name"` struct tag,
+// falling back to the field name; fields tagged `xmlrpc:"-"` are skipped.
+// Integers decode into bools (non-zero is true), floats and time.Time (as Unix
+// seconds), since that is how rTorrent reports them.
+// A value that doesn't fit its destination yields a *DecodeError.
+func Decode(src interface{}, dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.Errorf("xmlrpc: Decode requires a non-nil pointer, got %T", dst)
+	}
+	return decodeValue(src, rv.Elem(), "", "")
+}
+
+// DecodeMulticall decodes the rows of a multicall result, such as the one of
+// d.multicall2 or f.multicall, into the slice pointed to by `dst`.
+// `columns` are the commands that were requested for every row; they are
+// matched against the struct member names of the slice elements, and columns
+// without a matching member are ignored.
+func DecodeMulticall(src interface{}, columns []string, dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return errors.Errorf("xmlrpc: DecodeMulticall requires a pointer to a slice, got %T", dst)
+	}
+	slice := rv.Elem()
+
+	rows, ok := src.([]interface{})
+	if !ok {
+		return &DecodeError{Value: src, Type: slice.Type()}
+	}
+	out := reflect.MakeSlice(slice.Type(), len(rows), len(rows))
+	for i, row := range rows {
+		path := fmt.Sprintf("[%d]", i)
+		values, ok := row.([]interface{})
+		if !ok || len(values) != len(columns) {
+			return &DecodeError{Path: path, Value: row, Type: slice.Type().Elem()}
+		}
+		members := make(map[string]interface{}, len(columns))
+		for j, column := range columns {
+			members[column] = values[j]
+		}
+		if err := decodeValue(members, out.Index(i), path, ""); err != nil {
+			return err
+		}
+	}
+	slice.Set(out)
+	return nil
+}
+
+// FieldNames returns the XML-RPC member names of the struct (or pointer to
+// struct) `v`, in field order, as used by Decode.
+// This is handy to build the argument list of a multicall.
+func FieldNames(v interface{}) []string {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	fields := structFields(t)
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.name
+	}
+	return names
+}
+
+type fieldInfo struct {
+	index     int
+	name      string
+	goName    string
+	omitEmpty bool
+}
+
+// fieldTag parses the `xmlrpc:"name,omitempty"` tag of sf, falling back to
+// an `xml` tag and then to the field name.
+// ok is false for fields tagged "-", which are skipped.
+func fieldTag(sf reflect.StructField) (name string, omitEmpty bool, ok bool) {
+	tag, found := sf.Tag.Lookup("xmlrpc")
+	if !found {
+		tag = sf.Tag.Get("xml")
+	}
+	parts := strings.Split(tag, ",")
+	if parts[0] == "-" && len(parts) == 1 {
+		return "", false, false
+	}
+	name = parts[0]
+	if name == "" {
+		name = sf.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, true
+}
+
+// structFields lists the exported, non-skipped fields of t with their member names
+// It is shared by the encoder and Decode, so both directions agree on names
+func structFields(t reflect.Type) []fieldInfo {
+	var fields []fieldInfo
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" { // unexported
+			continue
+		}
+		name, omitEmpty, ok := fieldTag(sf)
+		if !ok {
+			continue
+		}
+		fields = append(fields, fieldInfo{index: i, name: name, goName: sf.Name, omitEmpty: omitEmpty})
+	}
+	return fields
+}
+
+func decodeValue(src interface{}, dst reflect.Value, path, field string) error {
+	if dst.Kind() == reflect.Interface && dst.NumMethod() == 0 {
+		if src == nil {
+			dst.Set(reflect.Zero(dst.Type()))
+		} else {
+			dst.Set(reflect.ValueOf(src))
+		}
+		return nil
+	}
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	if dst.Kind() == reflect.Ptr {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return decodeValue(src, dst.Elem(), path, field)
+	}
+
+	mismatch := &DecodeError{Path: path, Field: field, Value: src, Type: dst.Type()}
+
+	if dst.Type() == timeType {
+		switch v := src.(type) {
+		case time.Time:
+			dst.Set(reflect.ValueOf(v))
+		default:
+			i, ok := toInt64(src)
+			if !ok {
+				return mismatch
+			}
+			if i == 0 {
+				dst.Set(reflect.Zero(timeType))
+			} else {
+				dst.Set(reflect.ValueOf(time.Unix(i, 0)))
+			}
+		}
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.String:
+		switch v := src.(type) {
+		case string:
+			dst.SetString(v)
+		case []byte:
+			dst.SetString(string(v))
+		default:
+			return mismatch
+		}
+	case reflect.Bool:
+		if b, ok := src.(bool); ok {
+			dst.SetBool(b)
+			return nil
+		}
+		i, ok := toInt64(src)
+		if !ok {
+			return mismatch
+		}
+		dst.SetBool(i != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := toInt64(src)
+		if !ok || dst.OverflowInt(i) {
+			return mismatch
+		}
+		dst.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, ok := toInt64(src)
+		if !ok || i < 0 || dst.OverflowUint(uint64(i)) {
+			return mismatch
+		}
+		dst.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if v, ok := src.(float64); ok {
+			f = v
+		} else if i, ok := toInt64(src); ok {
+			f = float64(i)
+		} else {
+			return mismatch
+		}
+		if dst.OverflowFloat(f) {
+			return mismatch
+		}
+		dst.SetFloat(f)
+	case reflect.Slice:
+		if dst.Type().Elem().Kind() == reflect.Uint8 {
+			switch v := src.(type) {
+			case []byte:
+				dst.SetBytes(append([]byte(nil), v...))
+				return nil
+			case string:
+				dst.SetBytes([]byte(v))
+				return nil
+			}
+		}
+		values, ok := src.([]interface{})
+		if !ok {
+			return mismatch
+		}
+		out := reflect.MakeSlice(dst.Type(), len(values), len(values))
+		for i, v := range values {
+			if err := decodeValue(v, out.Index(i), fmt.Sprintf("%s[%d]", path, i), field); err != nil {
+				return err
+			}
+		}
+		dst.Set(out)
+	case reflect.Array:
+		values, ok := src.([]interface{})
+		if !ok || len(values) != dst.Len() {
+			return mismatch
+		}
+		for i, v := range values {
+			if err := decodeValue(v, dst.Index(i), fmt.Sprintf("%s[%d]", path, i), field); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		members, ok := src.(map[string]interface{})
+		if !ok || dst.Type().Key().Kind() != reflect.String {
+			return mismatch
+		}
+		out := reflect.MakeMapWithSize(dst.Type(), len(members))
+		for name, v := range members {
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := decodeValue(v, elem, fmt.Sprintf("%s[%q]", path, name), name); err != nil {
+				return err
+			}
+			out.SetMapIndex(reflect.ValueOf(name).Convert(dst.Type().Key()), elem)
+		}
+		dst.Set(out)
+	case reflect.Struct:
+		fields := structFields(dst.Type())
+		switch v := src.(type) {
+		case map[string]interface{}:
+			for _, f := range fields {
+				member, ok := v[f.name]
+				if !ok {
+					continue
+				}
+				if err := decodeValue(member, dst.Field(f.index), joinPath(path, f.goName), f.name); err != nil {
+					return err
+				}
+			}
+		case []interface{}:
+			if len(v) != len(fields) {
+				return mismatch
+			}
+			for i, f := range fields {
+				if err := decodeValue(v[i], dst.Field(f.index), joinPath(path, f.goName), f.name); err != nil {
+					return err
+				}
+			}
+		default:
+			return mismatch
+		}
+	default:
+		return mismatch
+	}
+	return nil
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// toInt64 converts any Go integer to an int64
+func toInt64(v interface{}) (int64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := rv.Uint()
+		if u > 1<<63-1 {
+			return 0, false
+		}
+		return int64(u), true
+	}
+	return 0, false
+}
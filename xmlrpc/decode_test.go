@@ -0,0 +1,128 @@
+package xmlrpc
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type decodeRow struct {
+	Name     string    `xmlrpc:"d.name="`
+	Size     int64     `xmlrpc:"d.size_bytes="`
+	Complete bool      `xmlrpc:"d.complete="`
+	Ratio    float64   `xmlrpc:"d.ratio="`
+	Created  time.Time `xmlrpc:"d.creation_date="`
+	Index    int       `xmlrpc:"-"`
+}
+
+func TestDecode(t *testing.T) {
+	t.Run("scalars", func(t *testing.T) {
+		var s string
+		require.NoError(t, Decode("name", &s))
+		require.Equal(t, "name", s)
+
+		var i int
+		require.NoError(t, Decode(42, &i))
+		require.Equal(t, 42, i)
+
+		var b bool
+		require.NoError(t, Decode(1, &b))
+		require.True(t, b)
+
+		var f float64
+		require.NoError(t, Decode(3, &f))
+		require.Equal(t, 3.0, f)
+
+		var small int8
+		err := Decode(300, &small)
+		require.Error(t, err)
+		require.IsType(t, &DecodeError{}, err)
+	})
+
+	t.Run("struct from struct", func(t *testing.T) {
+		var row decodeRow
+		err := Decode(map[string]interface{}{
+			"d.name=":       "ubuntu.iso",
+			"d.size_bytes=": 784334848,
+			"d.unknown=":    "ignored",
+		}, &row)
+		require.NoError(t, err)
+		require.Equal(t, decodeRow{Name: "ubuntu.iso", Size: 784334848}, row)
+	})
+
+	t.Run("struct from array", func(t *testing.T) {
+		var row decodeRow
+		err := Decode([]interface{}{"ubuntu.iso", 1, 1, 1500, 1556150400}, &row)
+		require.NoError(t, err)
+		require.Equal(t, "ubuntu.iso", row.Name)
+		require.Equal(t, int64(1), row.Size)
+		require.True(t, row.Complete)
+		require.Equal(t, 1500.0, row.Ratio)
+		require.Equal(t, time.Unix(1556150400, 0), row.Created)
+
+		err = Decode([]interface{}{"ubuntu.iso"}, &row)
+		require.Error(t, err)
+	})
+
+	t.Run("slices and maps", func(t *testing.T) {
+		var names []string
+		require.NoError(t, Decode([]interface{}{"a", "b"}, &names))
+		require.Equal(t, []string{"a", "b"}, names)
+
+		var m map[string]int
+		require.NoError(t, Decode(map[string]interface{}{"a": 1}, &m))
+		require.Equal(t, map[string]int{"a": 1}, m)
+
+		var data []byte
+		require.NoError(t, Decode([]byte("raw"), &data))
+		require.Equal(t, []byte("raw"), data)
+	})
+
+	t.Run("type mismatch", func(t *testing.T) {
+		var rows []decodeRow
+		err := Decode([]interface{}{
+			map[string]interface{}{"d.name=": "ok"},
+			map[string]interface{}{"d.name=": 12},
+		}, &rows)
+		require.Error(t, err)
+		decodeErr, ok := err.(*DecodeError)
+		require.True(t, ok)
+		require.Equal(t, "[1].Name", decodeErr.Path)
+		require.Equal(t, "d.name=", decodeErr.Field)
+		require.Equal(t, 12, decodeErr.Value)
+		require.True(t, strings.Contains(err.Error(), "[1].Name (d.name=)"), err.Error())
+	})
+
+	t.Run("requires pointer", func(t *testing.T) {
+		var s string
+		require.Error(t, Decode("x", s))
+		require.Error(t, Decode("x", nil))
+	})
+}
+
+func TestDecodeMulticall(t *testing.T) {
+	columns := []string{"d.size_bytes=", "d.name=", "d.custom1="}
+	var rows []decodeRow
+	err := DecodeMulticall([]interface{}{
+		[]interface{}{10, "first", "label"},
+		[]interface{}{20, "second", ""},
+	}, columns, &rows)
+	require.NoError(t, err)
+	require.Equal(t, []decodeRow{{Name: "first", Size: 10}, {Name: "second", Size: 20}}, rows)
+
+	err = DecodeMulticall([]interface{}{[]interface{}{10}}, columns, &rows)
+	require.Error(t, err)
+
+	err = DecodeMulticall("garbage", columns, &rows)
+	require.Error(t, err)
+}
+
+func TestFieldNames(t *testing.T) {
+	require.Equal(t,
+		[]string{"d.name=", "d.size_bytes=", "d.complete=", "d.ratio=", "d.creation_date="},
+		FieldNames(decodeRow{}))
+	require.Equal(t, FieldNames(decodeRow{}), FieldNames(&decodeRow{}))
+	require.Nil(t, FieldNames("not a struct"))
+}
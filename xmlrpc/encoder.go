@@ -0,0 +1,295 @@
+package xmlrpc
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	faultType = reflect.TypeOf(Fault{})
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// encoder streams the XML representation of values into a buffered writer.
+// Nothing is built up in memory besides the bufio buffer, so the cost of
+// encoding stays linear in the size of the output, and write errors are
+// reported once, by flush.
+type encoder struct {
+	w       *bufio.Writer
+	scratch [64]byte
+}
+
+func newEncoder(w io.Writer) *encoder {
+	if bw, ok := w.(*bufio.Writer); ok {
+		return &encoder{w: bw}
+	}
+	return &encoder{w: bufio.NewWriter(w)}
+}
+
+func (e *encoder) flush() error {
+	return e.w.Flush()
+}
+
+// writeEscaped writes s with the XML special characters escaped, see xmlSpecial
+func (e *encoder) writeEscaped(s string) {
+	last := 0
+	for i := 0; i < len(s); i++ {
+		var esc string
+		switch s[i] {
+		case '<':
+			esc = "&lt;"
+		case '>':
+			esc = "&gt;"
+		case '"':
+			esc = "&quot;"
+		case '\'':
+			esc = "&apos;"
+		case '&':
+			esc = "&amp;"
+		default:
+			continue
+		}
+		e.w.WriteString(s[last:i])
+		e.w.WriteString(esc)
+		last = i + 1
+	}
+	e.w.WriteString(s[last:])
+}
+
+func (e *encoder) writeTagged(tag string, inner []byte) {
+	e.w.WriteByte('<')
+	e.w.WriteString(tag)
+	e.w.WriteByte('>')
+	e.w.Write(inner)
+	e.w.WriteString("</")
+	e.w.WriteString(tag)
+	e.w.WriteByte('>')
+}
+
+func (e *encoder) writeBase64(b []byte) {
+	e.w.WriteString("<base64>")
+	enc := base64.NewEncoder(base64.StdEncoding, e.w)
+	enc.Write(b)
+	enc.Close()
+	e.w.WriteString("</base64>")
+}
+
+// encode writes the value r, typed if typ is true
+func (e *encoder) encode(r reflect.Value, typ bool) error {
+	if !r.IsValid() { // untyped nil
+		e.w.WriteString("<nil/>")
+		return nil
+	}
+	t := r.Type()
+	k := t.Kind()
+
+	if k == reflect.Interface {
+		if r.IsNil() {
+			e.w.WriteString("<nil/>")
+			return nil
+		}
+		return e.encode(r.Elem(), typ)
+	}
+	if t == faultType || t.Implements(errorType) {
+		if fp, ok := getFault(r.Interface()); ok {
+			_, err := fp.WriteXML(e.w)
+			return err
+		}
+	}
+	if t == timeType {
+		tim := r.Interface().(time.Time)
+		e.writeTagged("dateTime.iso8601", tim.AppendFormat(e.scratch[:0], FullXMLRpcTime))
+		return nil
+	}
+
+	switch k {
+	case reflect.Bool:
+		if r.Bool() {
+			e.w.WriteString("<boolean>1</boolean>")
+		} else {
+			e.w.WriteString("<boolean>0</boolean>")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.writeInt(r.Int(), typ)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := r.Uint()
+		if u > math.MaxInt64 {
+			return Errorf2(ErrUnsupported, "integer overflows i8: v=%v", u)
+		}
+		e.writeInt(int64(u), typ)
+	case reflect.Float32, reflect.Float64:
+		f := strconv.AppendFloat(e.scratch[:0], r.Float(), 'f', -1, t.Bits())
+		if typ {
+			e.writeTagged("double", f)
+		} else {
+			e.w.Write(f)
+		}
+	case reflect.String:
+		if typ {
+			e.w.WriteString("<string>")
+			e.writeEscaped(r.String())
+			e.w.WriteString("</string>")
+		} else {
+			e.writeEscaped(r.String())
+		}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && k == reflect.Slice {
+			e.writeBase64(r.Bytes())
+			return nil
+		}
+		e.w.WriteString("<array><data>")
+		n := r.Len()
+		for i := 0; i < n; i++ {
+			e.w.WriteString("<value>")
+			if err := e.encode(r.Index(i), typ); err != nil {
+				return err
+			}
+			e.w.WriteString("</value>")
+		}
+		e.w.WriteString("</data></array>")
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return Errorf2(ErrUnsupported, "map key must be a string: t=%v", t)
+		}
+		e.w.WriteString("<struct>")
+		iter := r.MapRange()
+		for iter.Next() {
+			e.w.WriteString("<member><name>")
+			e.writeEscaped(iter.Key().String())
+			e.w.WriteString("</name><value>")
+			if err := e.encode(iter.Value(), typ); err != nil {
+				return err
+			}
+			e.w.WriteString("</value></member>")
+		}
+		e.w.WriteString("</struct>")
+	case reflect.Struct:
+		e.w.WriteString("<struct>")
+		for _, f := range structFields(t) {
+			fv := r.Field(f.index)
+			if f.omitEmpty && isEmptyValue(fv) {
+				continue
+			}
+			e.w.WriteString("<member><name>")
+			e.writeEscaped(f.name)
+			e.w.WriteString("</name><value>")
+			if err := e.encode(fv, true); err != nil {
+				return err
+			}
+			e.w.WriteString("</value></member>")
+		}
+		e.w.WriteString("</struct>")
+	case reflect.Ptr:
+		if r.IsNil() {
+			e.w.WriteString("<nil/>")
+			return nil
+		}
+		return e.encode(r.Elem(), typ)
+	default:
+		return Errorf2(ErrUnsupported, "t=%v k=%s", t, k)
+	}
+	return nil
+}
+
+func (e *encoder) writeInt(i int64, typ bool) {
+	s := strconv.AppendInt(e.scratch[:0], i, 10)
+	if typ {
+		e.writeTagged(intTag(i), s)
+	} else {
+		e.w.Write(s)
+	}
+}
+
+// intTag returns the narrowest XML-RPC integer type holding i: "int" for
+// 32 bit values, the "i8" extension understood by rTorrent otherwise
+func intTag(i int64) string {
+	if i < math.MinInt32 || i > math.MaxInt32 {
+		return "i8"
+	}
+	return "int"
+}
+
+// isEmptyValue reports whether v is empty as far as omitempty is concerned:
+// false, 0, a nil pointer or interface, an empty array, slice, map or
+// string, or the zero time.Time
+func isEmptyValue(v reflect.Value) bool {
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).IsZero()
+	}
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// WriteXML writes v, typed if typ is true, into w Writer
+// Nil pointers, interfaces and untyped nils are written as the <nil/>
+// extension, time.Time as dateTime.iso8601, and structs honor
+// `xmlrpc:"name,omitempty"` tags (see Decode). Values that have no XML-RPC
+// representation, such as channels or funcs, yield an ErrUnsupported error.
+// The output is streamed through a buffer, w isn't written to in tiny pieces.
+func WriteXML(w io.Writer, v interface{}, typ bool) error {
+	r, ok := v.(reflect.Value) // go back from reflect.Value, if needed.
+	if !ok {
+		r = reflect.ValueOf(v)
+	}
+	e := newEncoder(w)
+	if err := e.encode(r, typ); err != nil {
+		return err
+	}
+	return e.flush()
+}
+
+// Marshal marshals the named thing (methodResponse if name == "", otherwise a methodCall)
+// into the w Writer
+// The document is streamed: large arguments such as .torrent payloads are
+// encoded straight into w, without intermediate copies.
+func Marshal(w io.Writer, name string, args ...interface{}) error {
+	e := newEncoder(w)
+	if name == "" {
+		e.w.WriteString("<methodResponse>")
+		if len(args) > 0 {
+			if fp, ok := getFault(args[0]); ok {
+				if _, err := fp.WriteXML(e.w); err != nil {
+					return err
+				}
+				e.w.WriteString("\n</methodResponse>")
+				return e.flush()
+			}
+		}
+	} else {
+		e.w.WriteString("<methodCall><methodName>")
+		e.writeEscaped(name)
+		e.w.WriteString("</methodName>\n")
+	}
+	e.w.WriteString("<params>\n")
+	for _, arg := range args {
+		e.w.WriteString("<param><value>")
+		if err := e.encode(reflect.ValueOf(arg), true); err != nil {
+			return err
+		}
+		e.w.WriteString("</value></param>\n")
+	}
+	if name == "" {
+		e.w.WriteString("</params></methodResponse>")
+	} else {
+		e.w.WriteString("</params></methodCall>")
+	}
+	return e.flush()
+}
@@ -7,9 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"reflect"
 	"strconv"
-	"strings"
 	"time"
 )
 
@@ -114,6 +112,10 @@ func (st *state) parseValue() (nv interface{}, e error) {
 			e = st.checkLast("value")
 		}
 		return
+	case "nil": // extension, also seen as <ex:nil/>
+		st.last = nil
+		e = st.p.Skip()
+		return
 	case "boolean", "string", "int", "i1", "i2", "i4", "i8", "double", "dateTime.iso8601", "base64": //simple
 		st.last = nil
 		if e = st.p.DecodeElement(&vn, &se); e != nil {
@@ -126,13 +128,9 @@ func (st *state) parseValue() (nv interface{}, e error) {
 		case "string":
 			nv = vn.Body
 		case "int", "i1", "i2", "i4":
-			var i64 int64
-			i64, e = strconv.ParseInt(vn.Body, 10, 32)
-			nv = int(i64)
+			nv, e = strconv.ParseInt(vn.Body, 10, 32)
 		case "i8":
-			var i64 int64
-			i64, e = strconv.ParseInt(vn.Body, 10, 64)
-			nv = int(i64)
+			nv, e = strconv.ParseInt(vn.Body, 10, 64)
 		case "double":
 			nv, e = strconv.ParseFloat(vn.Body, 64)
 		case "dateTime.iso8601":
@@ -298,307 +296,6 @@ func (st *state) checkLast(name string) (e error) {
 	return
 }
 
-func toXML(v interface{}, typ bool) (s string) {
-	r := reflect.ValueOf(v)
-	t := r.Type()
-	k := t.Kind()
-
-	if b, ok := v.([]byte); ok {
-		return "<base64>" + base64.StdEncoding.EncodeToString(b) + "</base64>"
-	}
-
-	switch k {
-	case reflect.Invalid:
-		panic("Unsupported type")
-	case reflect.Bool:
-		return fmt.Sprintf("<boolean>%v</boolean>", v)
-	case reflect.Int,
-		reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
-		reflect.Uint,
-		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		if typ {
-			return fmt.Sprintf("<int>%v</int>", v)
-		}
-		return fmt.Sprintf("%v", v)
-	case reflect.Uintptr:
-		panic("Unsupported type")
-	case reflect.Float32, reflect.Float64:
-		if typ {
-			return fmt.Sprintf("<double>%v</double>", v)
-		}
-		return fmt.Sprintf("%v", v)
-	case reflect.Complex64, reflect.Complex128:
-		panic("Unsupported type")
-	case reflect.Array, reflect.Slice:
-		s = "<array><data>"
-		for n := 0; n < r.Len(); n++ {
-			s += "<value>"
-			s += toXML(r.Index(n).Interface(), typ)
-			s += "</value>"
-		}
-		s += "</data></array>"
-		return s
-	case reflect.Chan:
-		panic("Unsupported type")
-	case reflect.Func:
-		panic("Unsupported type")
-	case reflect.Interface:
-		return toXML(r.Elem(), typ)
-	case reflect.Map:
-		s = "<struct>"
-		for _, key := range r.MapKeys() {
-			s += "<member>"
-			s += "<name>" + xmlEscape(key.Interface().(string)) + "</name>"
-			s += "<value>" + toXML(r.MapIndex(key).Interface(), typ) + "</value>"
-			s += "</member>"
-		}
-		return s + "</struct>"
-	case reflect.Ptr:
-		panic("Unsupported type")
-	case reflect.String:
-		if typ {
-			return fmt.Sprintf("<string>%v</string>", xmlEscape(v.(string)))
-		}
-		return xmlEscape(v.(string))
-	case reflect.Struct:
-		s = "<struct>"
-		for n := 0; n < r.NumField(); n++ {
-			s += "<member>"
-			s += "<name>" + t.Field(n).Name + "</name>"
-			s += "<value>" + toXML(r.FieldByIndex([]int{n}).Interface(), true) + "</value>"
-			s += "</member>"
-		}
-		return s + "</struct>"
-	case reflect.UnsafePointer:
-		return toXML(r.Elem(), typ)
-	}
-	return
-}
-
-// WriteXML writes v, typed if typ is true, into w Writer
-func WriteXML(w io.Writer, v interface{}, typ bool) (err error) {
-	var (
-		r  reflect.Value
-		ok bool
-	)
-	// go back from reflect.Value, if needed.
-	if r, ok = v.(reflect.Value); !ok {
-		r = reflect.ValueOf(v)
-	} else {
-		v = r.Interface()
-	}
-	if fp, ok := getFault(v); ok {
-		_, err = fp.WriteXML(w)
-		return
-	}
-	if b, ok := v.([]byte); ok {
-		length := base64.StdEncoding.EncodedLen(len(b))
-		dst := make([]byte, length)
-		base64.StdEncoding.Encode(dst, b)
-		_, err = taggedWrite(w, []byte("base64"), dst)
-		return
-	}
-	if tim, ok := v.(time.Time); ok {
-		_, err = taggedWriteString(w, "dateTime.iso8601", tim.Format(FullXMLRpcTime))
-		return
-	}
-	t := r.Type()
-	k := t.Kind()
-
-	switch k {
-	case reflect.Invalid, reflect.Uintptr, reflect.Complex64, reflect.Complex128, reflect.Chan, reflect.Func:
-		return Errorf2(ErrUnsupported, "v=%#v t=%v k=%s", v, t, k)
-	case reflect.Bool:
-		_, err = fmt.Fprintf(w, "<boolean>%v</boolean>", v)
-		return err
-	case reflect.Int,
-		reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
-		reflect.Uint,
-		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		if typ {
-			_, err = fmt.Fprintf(w, "<int>%v</int>", v)
-			return err
-		}
-		_, err = fmt.Fprintf(w, "%v", v)
-		return err
-	case reflect.Float32, reflect.Float64:
-		if typ {
-			_, err = fmt.Fprintf(w, "<double>%v</double>", v)
-			return err
-		}
-		_, err = fmt.Fprintf(w, "%v", v)
-		return err
-	case reflect.Array, reflect.Slice:
-		if _, err = io.WriteString(w, "<array><data>\n"); err != nil {
-			return
-		}
-		n := r.Len()
-		for i := 0; i < n; i++ {
-			if _, err = io.WriteString(w, "  <value>"); err != nil {
-				return
-			}
-			if err = WriteXML(w, r.Index(i).Interface(), typ); err != nil {
-				return
-			}
-			if _, err = io.WriteString(w, "</value>\n"); err != nil {
-				return
-			}
-		}
-		if _, err = io.WriteString(w, "</data></array>\n"); err != nil {
-			return
-		}
-	case reflect.Interface:
-		return WriteXML(w, r.Elem(), typ)
-	case reflect.Map:
-		if _, err = io.WriteString(w, "<struct>\n"); err != nil {
-			return
-		}
-		for _, key := range r.MapKeys() {
-			if _, err = io.WriteString(w, "  <member><name>"); err != nil {
-				return
-			}
-			if _, err = io.WriteString(w, xmlEscape(key.Interface().(string))); err != nil {
-				return
-			}
-			if _, err = io.WriteString(w, "</name><value>"); err != nil {
-				return
-			}
-			if err = WriteXML(w, r.MapIndex(key).Interface(), typ); err != nil {
-				return
-			}
-			if _, err = io.WriteString(w, "</value></member>\n"); err != nil {
-				return
-			}
-		}
-		_, err = io.WriteString(w, "</struct>")
-		return
-	case reflect.Ptr:
-		return WriteXML(w, reflect.Indirect(r), typ)
-	case reflect.String:
-		if typ {
-			_, err = fmt.Fprintf(w, "<string>%v</string>", xmlEscape(v.(string)))
-			return
-		}
-		_, err = io.WriteString(w, xmlEscape(v.(string)))
-		return
-	case reflect.Struct:
-		if _, err = io.WriteString(w, "<struct>"); err != nil {
-			return
-		}
-		n := r.NumField()
-		for i := 0; i < n; i++ {
-			c := t.Field(i).Name[:1]
-			if strings.ToLower(c) == c { //have to skip unexported fields
-				continue
-			}
-			if _, err = io.WriteString(w, "<member><name>"); err != nil {
-				return
-			}
-			if _, err = io.WriteString(w, xmlEscape(getStructFieldName(t.Field(i)))); err != nil {
-				return
-			}
-			if _, err = io.WriteString(w, "</name><value>"); err != nil {
-				return
-			}
-			if err = WriteXML(w, r.Field(i).Interface(), true); err != nil {
-				return
-			}
-			if _, err = io.WriteString(w, "</value></member>"); err != nil {
-				return err
-			}
-		}
-		_, err = io.WriteString(w, "</struct>")
-		return
-	case reflect.UnsafePointer:
-		return WriteXML(w, r.Elem(), typ)
-	}
-	return
-}
-
-func taggedWrite(w io.Writer, tag, inner []byte) (n int, err error) {
-	var j int
-	for _, elt := range [][]byte{[]byte("<"), tag, []byte(">"), inner,
-		[]byte("</"), tag, []byte(">")} {
-		j, err = w.Write(elt)
-		n += j
-		if err != nil {
-			return
-		}
-	}
-	return
-}
-func taggedWriteString(w io.Writer, tag, inner string) (n int, err error) {
-	if n, err = io.WriteString(w, "<"+tag+">"); err != nil {
-		return
-	}
-	var j int
-	j, err = io.WriteString(w, inner)
-	n += j
-	if err != nil {
-		return
-	}
-	j, err = io.WriteString(w, "</"+tag+">")
-	n += j
-	return
-}
-
-func getStructFieldName(sf reflect.StructField) string {
-	if sf.Tag.Get("xml") == "" {
-		return sf.Name
-	}
-	return sf.Tag.Get("xml")
-}
-
-// Marshal marshals the named thing (methodResponse if name == "", otherwise a methodCall)
-// into the w Writer
-func Marshal(w io.Writer, name string, args ...interface{}) (err error) {
-	if name == "" {
-		if _, err = io.WriteString(w, "<methodResponse>"); err != nil {
-			return
-		}
-		if len(args) > 0 {
-			fp, ok := getFault(args[0])
-			if ok {
-				_, err = fp.WriteXML(w)
-				if err == nil {
-					_, err = io.WriteString(w, "\n</methodResponse>")
-				}
-				return
-			}
-		}
-	} else {
-		if _, err = io.WriteString(w, "<methodCall><methodName>"); err != nil {
-			return
-		}
-		if _, err = io.WriteString(w, xmlEscape(name)); err != nil {
-			return
-		}
-		if _, err = io.WriteString(w, "</methodName>\n"); err != nil {
-			return
-		}
-	}
-	if _, err = io.WriteString(w, "<params>\n"); err != nil {
-		return
-	}
-	for _, arg := range args {
-		if _, err = io.WriteString(w, "  <param><value>"); err != nil {
-			return
-		}
-		if err = WriteXML(w, arg, true); err != nil {
-			return
-		}
-		if _, err = io.WriteString(w, "</value></param>\n"); err != nil {
-			return
-		}
-	}
-	if name == "" {
-		_, err = io.WriteString(w, "</params></methodResponse>")
-	} else {
-		_, err = io.WriteString(w, "</params></methodCall>")
-	}
-	return err
-}
-
 func getFault(v interface{}) (*Fault, bool) {
 	if f, ok := v.(Fault); ok {
 		return &f, true
@@ -615,6 +312,28 @@ func getFault(v interface{}) (*Fault, bool) {
 	return nil, false
 }
 
+// faultFromMap converts a decoded fault struct into a Fault
+func faultFromMap(fmap map[string]interface{}) (*Fault, error) {
+	fault := &Fault{Code: -1, Message: ""}
+	code, ok := fmap["faultCode"]
+	if !ok {
+		return nil, fmt.Errorf("no faultCode in fault: %v", fmap)
+	}
+	fcode, ok := toInt64(code)
+	if !ok {
+		return nil, fmt.Errorf("faultCode not int? %v", code)
+	}
+	fault.Code = int(fcode)
+	msg, ok := fmap["faultString"]
+	if !ok {
+		return nil, fmt.Errorf("no faultString in fault: %v", fmap)
+	}
+	if fault.Message, ok = msg.(string); !ok {
+		return nil, fmt.Errorf("faultString not strin? %v", msg)
+	}
+	return fault, nil
+}
+
 // Unmarshal unmarshals the thing (methodResponse, methodCall or fault),
 // returns the name of the method call in the first return argument;
 // the params of the call or the response
@@ -641,25 +360,7 @@ func Unmarshal(r io.Reader) (name string, params []interface{}, fault *Fault, e
 				e = fmt.Errorf("fault not fault: %+v", v)
 				return
 			}
-			fault = &Fault{Code: -1, Message: ""}
-			code, ok := fmap["faultCode"]
-			if !ok {
-				e = fmt.Errorf("no faultCode in fault: %v", fmap)
-				return
-			}
-			fcode, ok := code.(int)
-			if !ok {
-				e = fmt.Errorf("faultCode not int? %v", code)
-				return
-			}
-			fault.Code = int(fcode)
-			msg, ok := fmap["faultString"]
-			if !ok {
-				e = fmt.Errorf("no faultString in fault: %v", fmap)
-				return
-			}
-			if fault.Message, ok = msg.(string); !ok {
-				e = fmt.Errorf("faultString not strin? %v", msg)
+			if fault, e = faultFromMap(fmap); e != nil {
 				return
 			}
 			e = st.checkLast("fault")
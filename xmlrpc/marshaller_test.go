@@ -0,0 +1,192 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func marshalValue(t *testing.T, v interface{}) string {
+	var b bytes.Buffer
+	require.NoError(t, WriteXML(&b, v, true))
+	return b.String()
+}
+
+func roundTrip(t *testing.T, args ...interface{}) []interface{} {
+	var b bytes.Buffer
+	require.NoError(t, Marshal(&b, "test.method", args...))
+	name, params, fault, err := Unmarshal(&b)
+	require.NoError(t, err)
+	require.Nil(t, fault)
+	require.Equal(t, "test.method", name)
+	return params
+}
+
+func TestIntegers(t *testing.T) {
+	t.Run("encode", func(t *testing.T) {
+		require.Equal(t, "<int>42</int>", marshalValue(t, 42))
+		require.Equal(t, "<int>-2147483648</int>", marshalValue(t, int32(math.MinInt32)))
+		require.Equal(t, "<i8>2147483648</i8>", marshalValue(t, int64(math.MaxInt32)+1))
+		require.Equal(t, "<i8>-2147483649</i8>", marshalValue(t, int64(math.MinInt32)-1))
+		require.Equal(t, "<i8>4294967295</i8>", marshalValue(t, uint32(math.MaxUint32)))
+		require.Equal(t, "<i8>9223372036854775807</i8>", marshalValue(t, uint64(math.MaxInt64)))
+
+		var b bytes.Buffer
+		require.Error(t, WriteXML(&b, uint64(math.MaxUint64), true))
+	})
+
+	t.Run("decode", func(t *testing.T) {
+		params := roundTrip(t, 7, int64(5)<<40, uint16(3))
+		require.Equal(t, []interface{}{int64(7), int64(5) << 40, int64(3)}, params)
+
+		_, params, _, err := Unmarshal(strings.NewReader(`<methodResponse><params>
+			<param><value><i4>12</i4></value></param>
+			<param><value><i8>784334848000</i8></value></param>
+		</params></methodResponse>`))
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{int64(12), int64(784334848000)}, params)
+	})
+}
+
+func TestWriteXML(t *testing.T) {
+	require.Equal(t, "<string>a &lt;b&gt; &amp; &quot;c&apos;</string>", marshalValue(t, `a <b> & "c'`))
+	require.Equal(t, "<boolean>1</boolean>", marshalValue(t, true))
+	require.Equal(t, "<double>0.5</double>", marshalValue(t, 0.5))
+	require.Equal(t, "<base64>cmF3</base64>", marshalValue(t, []byte("raw")))
+	require.Equal(t,
+		"<array><data><value><int>1</int></value><value><array><data><value><string>x</string></value></data></array></value></data></array>",
+		marshalValue(t, []interface{}{1, []string{"x"}}))
+	require.Equal(t,
+		"<struct><member><name>k</name><value><string>v</string></value></member></struct>",
+		marshalValue(t, map[string]string{"k": "v"}))
+
+	var b bytes.Buffer
+	require.Error(t, WriteXML(&b, map[int]string{1: "v"}, true))
+	require.Error(t, WriteXML(&b, make(chan int), true))
+
+	params := roundTrip(t, "x & y", []byte{0, 1, 2}, true, 1.25)
+	require.Equal(t, []interface{}{"x & y", []byte{0, 1, 2}, true, 1.25}, params)
+}
+
+// multicallArgs builds a system.multicall request setting the priority of
+// every file of a large torrent
+func multicallArgs(files int) []interface{} {
+	calls := make([]interface{}, files)
+	for i := range calls {
+		calls[i] = map[string]interface{}{
+			"methodName": "f.priority.set",
+			"params":     []interface{}{"B7B0FBAB74A85D4AC170662C645982A862826455:f" + strings.Repeat("1", i%5+1), 0},
+		}
+	}
+	return []interface{}{calls}
+}
+
+func BenchmarkMarshalMulticall(b *testing.B) {
+	args := multicallArgs(5000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := Marshal(ioutil.Discard, "system.multicall", args...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalBase64(b *testing.B) {
+	data := bytes.Repeat([]byte("d8:announce"), 1<<17) // ~1.4MiB .torrent payload
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		if err := Marshal(ioutil.Discard, "load.raw", "", data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalStrings(b *testing.B) {
+	names := make([]interface{}, 10000)
+	for i := range names {
+		names[i] = "ubuntu-19.04-live-server-amd64 & friends <" + strings.Repeat("x", i%32) + ">.iso"
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := Marshal(ioutil.Discard, "", names); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+type taggedStruct struct {
+	Name     string     `xmlrpc:"name"`
+	Label    string     `xmlrpc:"label,omitempty"`
+	Priority *int       `xmlrpc:"priority,omitempty"`
+	Parent   *string    `xmlrpc:"parent"`
+	Added    time.Time  `xmlrpc:"added,omitempty"`
+	Legacy   int        `xml:"legacy"`
+	Skipped  string     `xmlrpc:"-"`
+	Nested   *taggedRow `xmlrpc:"nested,omitempty"`
+	internal string
+}
+
+type taggedRow struct {
+	Index int `xmlrpc:"index"`
+}
+
+func TestMarshalTypes(t *testing.T) {
+	t.Run("nil", func(t *testing.T) {
+		var p *int
+		require.Equal(t, "<nil/>", marshalValue(t, p))
+		require.Equal(t, "<nil/>", marshalValue(t, nil))
+		require.Equal(t, "<array><data><value><nil/></value></data></array>", marshalValue(t, []interface{}{nil}))
+	})
+
+	t.Run("pointers", func(t *testing.T) {
+		i := 3
+		require.Equal(t, "<int>3</int>", marshalValue(t, &i))
+		require.Equal(t, "<struct><member><name>index</name><value><int>1</int></value></member></struct>",
+			marshalValue(t, &taggedRow{Index: 1}))
+	})
+
+	t.Run("time", func(t *testing.T) {
+		tim := time.Date(2019, 4, 25, 10, 30, 0, 0, time.FixedZone("", 2*3600))
+		require.Equal(t, "<dateTime.iso8601>2019-04-25T10:30:00+02:00</dateTime.iso8601>", marshalValue(t, tim))
+		params := roundTrip(t, tim)
+		require.True(t, tim.Equal(params[0].(time.Time)))
+	})
+
+	t.Run("struct tags", func(t *testing.T) {
+		require.Equal(t,
+			"<struct>"+
+				"<member><name>name</name><value><string>a</string></value></member>"+
+				"<member><name>parent</name><value><nil/></value></member>"+
+				"<member><name>legacy</name><value><int>2</int></value></member>"+
+				"</struct>",
+			marshalValue(t, taggedStruct{Name: "a", Legacy: 2, Skipped: "x", internal: "y"}))
+
+		prio := 0
+		out := marshalValue(t, taggedStruct{Label: "l", Priority: &prio, Nested: &taggedRow{}})
+		require.Contains(t, out, "<member><name>label</name><value><string>l</string></value></member>")
+		require.Contains(t, out, "<member><name>priority</name><value><int>0</int></value></member>")
+		require.Contains(t, out, "<member><name>nested</name>")
+	})
+
+	t.Run("round trip with nil", func(t *testing.T) {
+		params := roundTrip(t, taggedStruct{Name: "a"})
+		var decoded taggedStruct
+		require.NoError(t, Decode(params[0], &decoded))
+		require.Equal(t, taggedStruct{Name: "a"}, decoded)
+	})
+
+	t.Run("unsupported kinds", func(t *testing.T) {
+		var b bytes.Buffer
+		err := WriteXML(&b, struct{ F func() }{}, true)
+		require.Error(t, err)
+		require.True(t, ErrEq(err, ErrUnsupported))
+		require.Error(t, WriteXML(&b, complex(1, 2), true))
+		require.Error(t, Marshal(&b, "x", make(chan int)))
+	})
+}
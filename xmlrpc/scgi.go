@@ -0,0 +1,320 @@
+package xmlrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// SCGIScheme is the endpoint scheme selecting the SCGI transport.
+// Use "scgi:///path/to/rpc.socket" for a Unix socket and
+// "scgi://host:port" for a TCP listener.
+const SCGIScheme = "scgi"
+
+// IsSCGI reports whether addr is an SCGI endpoint
+func IsSCGI(addr string) bool {
+	return strings.HasPrefix(addr, SCGIScheme+"://")
+}
+
+// SCGITransport is an http.RoundTripper that talks SCGI instead of HTTP.
+// It lets a Client reach rTorrent directly on the socket opened by
+// `network.scgi.open_local` or `network.scgi.open_port`, without a web
+// server in between.
+type SCGITransport struct {
+	// Network is "unix" or "tcp"
+	Network string
+	// Address is the socket path or host:port to dial
+	Address string
+	// Dialer is used to open connections, a zero net.Dialer is used if nil
+	Dialer *net.Dialer
+}
+
+// NewSCGITransport returns a new instance of SCGITransport for the given
+// "scgi://" endpoint.
+// An endpoint with an empty host ("scgi:///path/to/rpc.socket") dials a Unix
+// socket, anything else ("scgi://host:port") dials TCP.
+func NewSCGITransport(addr string) *SCGITransport {
+	rest := strings.TrimPrefix(addr, SCGIScheme+"://")
+	if strings.HasPrefix(rest, "/") {
+		return &SCGITransport{Network: "unix", Address: rest}
+	}
+	if i := strings.Index(rest, "/"); i >= 0 {
+		rest = rest[:i]
+	}
+	return &SCGITransport{Network: "tcp", Address: rest}
+}
+
+// RoundTrip sends the request as a single SCGI request and parses the
+// CGI-style response.
+// SCGI requires the content length up front, so the request body is
+// buffered before it is sent.
+func (t *SCGITransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read request body")
+		}
+	}
+
+	dialer := t.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	ctx := req.Context()
+	conn, err := dialer.DialContext(ctx, t.Network, t.Address)
+	if err != nil {
+		return nil, errors.Wrap(err, "SCGI dial failed")
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c := &scgiConn{Conn: conn, done: make(chan struct{})}
+	go c.watch(ctx)
+
+	if _, err := c.Write(append(scgiHeader(req, len(body)), body...)); err != nil {
+		c.Close()
+		return nil, c.err(ctx, errors.Wrap(err, "SCGI write failed"))
+	}
+
+	resp, err := readSCGIResponse(bufio.NewReader(c), c, req)
+	if err != nil {
+		c.Close()
+		return nil, c.err(ctx, err)
+	}
+	return resp, nil
+}
+
+// scgiConn closes the underlying connection when the request context is
+// done, which unblocks any pending read or write
+type scgiConn struct {
+	net.Conn
+	done chan struct{}
+	once sync.Once
+}
+
+func (c *scgiConn) watch(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		c.Conn.Close()
+	case <-c.done:
+	}
+}
+
+func (c *scgiConn) Close() error {
+	c.once.Do(func() { close(c.done) })
+	return c.Conn.Close()
+}
+
+// err prefers the context error over the I/O error it caused
+func (c *scgiConn) err(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// scgiHeader returns the netstring encoded SCGI header block for req.
+// CONTENT_LENGTH has to come first, as required by the SCGI spec.
+func scgiHeader(req *http.Request, length int) []byte {
+	contentType := req.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "text/xml"
+	}
+	fields := []string{
+		"CONTENT_LENGTH", strconv.Itoa(length),
+		"SCGI", "1",
+		"REQUEST_METHOD", req.Method,
+		"REQUEST_URI", "/RPC2",
+		"SERVER_PROTOCOL", "HTTP/1.1",
+		"CONTENT_TYPE", contentType,
+	}
+
+	var h bytes.Buffer
+	for _, f := range fields {
+		h.WriteString(f)
+		h.WriteByte(0)
+	}
+
+	var b bytes.Buffer
+	b.WriteString(strconv.Itoa(h.Len()))
+	b.WriteByte(':')
+	b.Write(h.Bytes())
+	b.WriteByte(',')
+	return b.Bytes()
+}
+
+// readSCGIResponse parses a CGI-style response: a MIME header block with an
+// optional "Status" header, followed by the body.
+func readSCGIResponse(br *bufio.Reader, conn io.Closer, req *http.Request) (*http.Response, error) {
+	header, err := textproto.NewReader(br).ReadMIMEHeader()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read SCGI response header")
+	}
+
+	resp := &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header(header),
+		ContentLength: -1,
+		Request:       req,
+	}
+	if status := header.Get("Status"); status != "" {
+		code, err := strconv.Atoi(strings.SplitN(status, " ", 2)[0])
+		if err != nil {
+			return nil, errors.Errorf("malformed SCGI status: %q", status)
+		}
+		resp.Status = status
+		resp.StatusCode = code
+		resp.Header.Del("Status")
+	}
+
+	var body io.Reader = br
+	if cl := header.Get("Content-Length"); cl != "" {
+		n, err := strconv.ParseInt(cl, 10, 64)
+		if err != nil {
+			return nil, errors.Errorf("malformed SCGI content length: %q", cl)
+		}
+		resp.ContentLength = n
+		body = io.LimitReader(br, n)
+	}
+	resp.Body = &scgiBody{Reader: body, conn: conn}
+	return resp, nil
+}
+
+type scgiBody struct {
+	io.Reader
+	conn io.Closer
+}
+
+func (b *scgiBody) Close() error {
+	return b.conn.Close()
+}
+
+// ServeSCGI accepts SCGI connections on l and serves each request with
+// handler, answering with a CGI-style response, the way rTorrent does.
+// It's the server side counterpart of SCGITransport, mostly useful to fake
+// rTorrent in tests. ServeSCGI always returns a non-nil error, the one
+// returned by l.Accept once l is closed.
+func ServeSCGI(l net.Listener, handler http.Handler) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go serveSCGIConn(conn, handler)
+	}
+}
+
+func serveSCGIConn(conn net.Conn, handler http.Handler) {
+	defer conn.Close()
+
+	br := bufio.NewReader(conn)
+	req, err := readSCGIRequest(br)
+	if err != nil {
+		fmt.Fprintf(conn, "Status: 400 Bad Request\r\nContent-Type: text/plain\r\n\r\n%v", err)
+		return
+	}
+	rw := &scgiResponseWriter{header: make(http.Header)}
+	handler.ServeHTTP(rw, req)
+	rw.writeTo(conn)
+}
+
+// readSCGIRequest parses the netstring encoded SCGI headers and wraps the
+// request body that follows them into an http.Request
+func readSCGIRequest(br *bufio.Reader) (*http.Request, error) {
+	size, err := br.ReadString(':')
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read SCGI header length")
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(size, ":"))
+	if err != nil || n < 0 {
+		return nil, errors.Errorf("malformed SCGI header length: %q", size)
+	}
+	raw := make([]byte, n+1)
+	if _, err := io.ReadFull(br, raw); err != nil {
+		return nil, errors.Wrap(err, "failed to read SCGI headers")
+	}
+	if raw[n] != ',' {
+		return nil, errors.New("malformed SCGI netstring")
+	}
+
+	env := make(map[string]string)
+	fields := strings.Split(string(raw[:n]), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		env[fields[i]] = fields[i+1]
+	}
+	length, err := strconv.ParseInt(env["CONTENT_LENGTH"], 10, 64)
+	if err != nil {
+		return nil, errors.Errorf("malformed SCGI CONTENT_LENGTH: %q", env["CONTENT_LENGTH"])
+	}
+
+	method := env["REQUEST_METHOD"]
+	if method == "" {
+		method = http.MethodPost
+	}
+	uri := env["REQUEST_URI"]
+	if uri == "" {
+		uri = "/"
+	}
+	req, err := http.NewRequest(method, uri, io.LimitReader(br, length))
+	if err != nil {
+		return nil, errors.Wrap(err, "malformed SCGI request")
+	}
+	req.ContentLength = length
+	req.RequestURI = uri
+	if ct := env["CONTENT_TYPE"]; ct != "" {
+		req.Header.Set("Content-Type", ct)
+	}
+	return req, nil
+}
+
+// scgiResponseWriter buffers the response, since the CGI-style header
+// carries its Content-Length
+type scgiResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *scgiResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *scgiResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *scgiResponseWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(b)
+}
+
+func (w *scgiResponseWriter) writeTo(conn io.Writer) error {
+	w.WriteHeader(http.StatusOK)
+	w.header.Set("Content-Length", strconv.Itoa(w.body.Len()))
+	bw := bufio.NewWriter(conn)
+	fmt.Fprintf(bw, "Status: %d %s\r\n", w.status, http.StatusText(w.status))
+	w.header.Write(bw)
+	bw.WriteString("\r\n")
+	bw.Write(w.body.Bytes())
+	return bw.Flush()
+}
@@ -0,0 +1,142 @@
+package xmlrpc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// serveSCGIOnce accepts a single connection on l, records the SCGI headers
+// it received and answers with a methodResponse carrying `result`.
+func serveSCGIOnce(t *testing.T, l net.Listener, result interface{}) <-chan map[string]string {
+	headers := make(chan map[string]string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		br := bufio.NewReader(conn)
+		size, err := br.ReadString(':')
+		if err != nil {
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimSuffix(size, ":"))
+		raw := make([]byte, n+1) // trailing ','
+		if _, err := io.ReadFull(br, raw); err != nil {
+			return
+		}
+		fields := strings.Split(string(raw[:n]), "\x00")
+		h := make(map[string]string)
+		for i := 0; i+1 < len(fields); i += 2 {
+			h[fields[i]] = fields[i+1]
+		}
+		length, _ := strconv.Atoi(h["CONTENT_LENGTH"])
+		body := make([]byte, length)
+		if _, err := io.ReadFull(br, body); err != nil {
+			return
+		}
+		h["BODY"] = string(body)
+		headers <- h
+
+		var resp bytes.Buffer
+		if err := Marshal(&resp, "", result); err != nil {
+			return
+		}
+		fmt.Fprintf(conn, "Status: 200 OK\r\nContent-Type: text/xml\r\nContent-Length: %d\r\n\r\n", resp.Len())
+		conn.Write(resp.Bytes())
+	}()
+	return headers
+}
+
+func TestNewSCGITransport(t *testing.T) {
+	tr := NewSCGITransport("scgi:///var/run/rtorrent/rpc.socket")
+	require.Equal(t, "unix", tr.Network)
+	require.Equal(t, "/var/run/rtorrent/rpc.socket", tr.Address)
+
+	tr = NewSCGITransport("scgi://localhost:5000")
+	require.Equal(t, "tcp", tr.Network)
+	require.Equal(t, "localhost:5000", tr.Address)
+
+	require.True(t, IsSCGI("scgi://localhost:5000"))
+	require.False(t, IsSCGI("http://localhost/RPC2"))
+}
+
+func TestSCGIClient(t *testing.T) {
+	t.Run("unix socket", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "scgi")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		socket := filepath.Join(dir, "rpc.socket")
+		l, err := net.Listen("unix", socket)
+		require.NoError(t, err)
+		defer l.Close()
+
+		headers := serveSCGIOnce(t, l, "my-host")
+		client := NewClient("scgi://"+socket, false)
+		result, err := client.Call("system.hostname")
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{"my-host"}, result)
+
+		h := <-headers
+		require.Equal(t, "1", h["SCGI"])
+		require.Equal(t, "POST", h["REQUEST_METHOD"])
+		require.Equal(t, strconv.Itoa(len(h["BODY"])), h["CONTENT_LENGTH"])
+		require.Contains(t, h["BODY"], "<methodName>system.hostname</methodName>")
+	})
+
+	t.Run("tcp", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer l.Close()
+
+		serveSCGIOnce(t, l, "0.0.0.0")
+		client := NewClient("scgi://"+l.Addr().String(), false)
+		result, err := client.Call("network.bind_address")
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{"0.0.0.0"}, result)
+	})
+
+	t.Run("dial failure", func(t *testing.T) {
+		client := NewClient("scgi:///nonexistent/rpc.socket", false)
+		_, err := client.Call("system.hostname")
+		require.Error(t, err)
+	})
+}
+
+func TestServeSCGI(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go ServeSCGI(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/RPC2", r.URL.Path)
+		name, params, _, err := Unmarshal(r.Body)
+		require.NoError(t, err)
+		if name != "echo" {
+			Marshal(w, "", &Fault{Code: -506, Message: "Method not defined"})
+			return
+		}
+		Marshal(w, "", params[0])
+	}))
+
+	client := NewClient(SCGIScheme+"://"+l.Addr().String(), false)
+	result, err := client.Call("echo", "hello")
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"hello"}, result)
+
+	_, err = client.Call("bogus")
+	require.Error(t, err)
+}
@@ -0,0 +1,200 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Fault codes used by Server, the same as rTorrent's
+const (
+	// FaultInternal is returned for method errors that aren't a Fault
+	FaultInternal = -500
+	// FaultInvalidArgument is rTorrent's input_error: bad target or params
+	FaultInvalidArgument = -501
+	// FaultParse is returned when the request can't be parsed
+	FaultParse = -503
+	// FaultMethodNotFound is returned for methods that aren't registered
+	FaultMethodNotFound = -506
+)
+
+// MethodFunc implements an XML-RPC method: it receives the decoded params
+// and returns the value to send back. Returning a *Fault (possibly wrapped)
+// sends that fault, any other error is sent as a FaultInternal fault.
+type MethodFunc func(args ...interface{}) (interface{}, error)
+
+// Server is an http.Handler answering XML-RPC calls with the methods
+// registered on it. It also provides system.listMethods, system.methodHelp
+// and system.multicall. Serve it over SCGI with ServeSCGI.
+type Server struct {
+	mu      sync.RWMutex
+	methods map[string]MethodFunc
+	help    map[string]string
+}
+
+// NewServer returns a Server with only the system.* methods registered
+func NewServer() *Server {
+	s := &Server{
+		methods: map[string]MethodFunc{},
+		help:    map[string]string{},
+	}
+	s.RegisterHelp("system.listMethods", s.listMethods,
+		"Returns the names of all the methods")
+	s.RegisterHelp("system.methodHelp", s.methodHelp,
+		"Returns the help text of the given method")
+	s.RegisterHelp("system.multicall", s.multicall,
+		"Calls several methods at once, returns an array of results and faults")
+	return s
+}
+
+// Register adds the method `name`, or replaces it while keeping its help
+func (s *Server) Register(name string, fn MethodFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.methods[name] = fn
+}
+
+// RegisterHelp is like Register, with the text returned by system.methodHelp
+func (s *Server) RegisterHelp(name string, fn MethodFunc, help string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.methods[name] = fn
+	s.help[name] = help
+}
+
+// Lookup returns the method registered as `name`, e.g. to wrap it
+func (s *Server) Lookup(name string) (MethodFunc, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fn, ok := s.methods[name]
+	return fn, ok
+}
+
+// Methods returns the sorted names of the registered methods
+func (s *Server) Methods() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.methods))
+	for name := range s.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Call runs the method `name` as if it was called over XML-RPC; errors are
+// converted into a *Fault
+func (s *Server) Call(name string, args ...interface{}) (interface{}, error) {
+	fn, ok := s.Lookup(name)
+	if !ok {
+		return nil, &Fault{Code: FaultMethodNotFound, Message: fmt.Sprintf("Method '%s' not defined", name)}
+	}
+	result, err := fn(args...)
+	if err != nil {
+		return nil, toFault(err)
+	}
+	return result, nil
+}
+
+// ServeHTTP answers a single methodCall with a methodResponse
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "XML-RPC requests must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var result interface{}
+	name, params, _, err := Unmarshal(r.Body)
+	if err == nil {
+		result, err = s.Call(name, params...)
+	} else {
+		err = &Fault{Code: FaultParse, Message: err.Error()}
+	}
+
+	// the response is buffered so that encoding errors can still be reported
+	var buf bytes.Buffer
+	if err == nil {
+		err = Marshal(&buf, "", result)
+		if err != nil {
+			buf.Reset()
+			err = toFault(err)
+		}
+	}
+	if err != nil {
+		if err := Marshal(&buf, "", err); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "text/xml")
+	w.Write(buf.Bytes())
+}
+
+// toFault returns the *Fault within err, or a FaultInternal one
+func toFault(err error) *Fault {
+	var fp *Fault
+	if errors.As(err, &fp) {
+		return fp
+	}
+	var f Fault
+	if errors.As(err, &f) {
+		return &f
+	}
+	return &Fault{Code: FaultInternal, Message: err.Error()}
+}
+
+func (s *Server) listMethods(args ...interface{}) (interface{}, error) {
+	return s.Methods(), nil
+}
+
+func (s *Server) methodHelp(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, &Fault{Code: FaultInvalidArgument, Message: "Wrong argument count."}
+	}
+	name, _ := args[0].(string)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.methods[name]; !ok {
+		return nil, &Fault{Code: FaultMethodNotFound, Message: fmt.Sprintf("Method '%s' not defined", name)}
+	}
+	return s.help[name], nil
+}
+
+// multicall runs each {methodName, params} call in turn. A successful call
+// yields a one element array holding its result, a failed one its fault
+// struct, and the other calls are still run.
+func (s *Server) multicall(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, &Fault{Code: FaultInvalidArgument, Message: "Wrong argument count."}
+	}
+	calls, ok := args[0].([]interface{})
+	if !ok {
+		return nil, &Fault{Code: FaultInvalidArgument, Message: "system.multicall expects an array."}
+	}
+	results := make([]interface{}, len(calls))
+	for i, c := range calls {
+		call, _ := c.(map[string]interface{})
+		name, _ := call["methodName"].(string)
+		params, _ := call["params"].([]interface{})
+
+		var result interface{}
+		var err error
+		if name == "system.multicall" {
+			err = &Fault{Code: FaultInvalidArgument, Message: "Recursive system.multicall forbidden."}
+		} else {
+			result, err = s.Call(name, params...)
+		}
+		if err != nil {
+			f := toFault(err)
+			results[i] = map[string]interface{}{"faultCode": f.Code, "faultString": f.Message}
+			continue
+		}
+		results[i] = []interface{}{result}
+	}
+	return results, nil
+}
@@ -0,0 +1,107 @@
+package xmlrpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	s := NewServer()
+	s.RegisterHelp("add", func(args ...interface{}) (interface{}, error) {
+		var sum int64
+		for _, a := range args {
+			i, ok := a.(int64)
+			if !ok {
+				return nil, &Fault{Code: FaultInvalidArgument, Message: "Not a value."}
+			}
+			sum += i
+		}
+		return sum, nil
+	}, "Adds integers")
+	s.Register("fail", func(args ...interface{}) (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	s.Register("wrapped", func(args ...interface{}) (interface{}, error) {
+		return nil, errors.Wrap(&Fault{Code: 42, Message: "inner"}, "outer")
+	})
+	s.Register("unsupported", func(args ...interface{}) (interface{}, error) {
+		return make(chan int), nil
+	})
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	client := NewClient(srv.URL, false)
+
+	t.Run("call", func(t *testing.T) {
+		result, err := client.Call("add", 1, 2, int64(1)<<40)
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{int64(3) + int64(1)<<40}, result)
+	})
+
+	t.Run("faults", func(t *testing.T) {
+		for method, code := range map[string]int{
+			"bogus":   FaultMethodNotFound,
+			"fail":    FaultInternal,
+			"wrapped": 42,
+		} {
+			_, err := s.Call(method)
+			require.Error(t, err, method)
+			require.Equal(t, code, err.(*Fault).Code, method)
+
+			_, err = client.Call(method)
+			require.Error(t, err, method)
+		}
+
+		// results that can't be encoded still get a well-formed fault
+		_, err := client.Call("unsupported")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "-500")
+	})
+
+	t.Run("list methods", func(t *testing.T) {
+		result, err := client.Call("system.listMethods")
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{[]interface{}{
+			"add", "fail", "system.listMethods", "system.methodHelp",
+			"system.multicall", "unsupported", "wrapped",
+		}}, result)
+	})
+
+	t.Run("method help", func(t *testing.T) {
+		result, err := client.Call("system.methodHelp", "add")
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{"Adds integers"}, result)
+	})
+
+	t.Run("multicall", func(t *testing.T) {
+		results, err := client.NewBatch().
+			Add("add", 1, 2).
+			Add("add", "x").
+			Add("system.multicall", []interface{}{}).
+			Send()
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		require.Equal(t, int64(3), results[0].Value)
+		require.Equal(t, &Fault{Code: FaultInvalidArgument, Message: "Not a value."}, results[1].Fault)
+		require.NotNil(t, results[2].Fault)
+	})
+
+	t.Run("parse error and GET", func(t *testing.T) {
+		resp, err := http.Get(srv.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Body = http.NoBody
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		_, _, fault, err := Unmarshal(w.Body)
+		require.NoError(t, err)
+		require.NotNil(t, fault)
+		require.Equal(t, FaultParse, fault.Code)
+	})
+}